@@ -1,56 +1,145 @@
 package dingtalkbot
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
 )
 
 var (
 	openApiGetAccessToken = "/v1.0/oauth2/accessToken"
 	openApiSendMessage    = "/v1.0/robot/groupMessages/send"
+	openApiSendOtoMessage = "/v1.0/robot/oToMessages/batchSend"
 )
 
-func getAccessToken(clientId, clientSecret string) (accessToken string, expireInSec int, err error) {
-	body := map[string]any{
-		"appKey":    clientId,
-		"appSecret": clientSecret,
-	}
-	respBody, err := post(openApiGetAccessToken, body, nil)
+type accessTokenRequest struct {
+	AppKey    string `json:"appKey"`
+	AppSecret string `json:"appSecret"`
+}
+
+type accessTokenResponse struct {
+	AccessToken string `json:"accessToken"`
+	ExpireIn    int    `json:"expireIn"`
+}
+
+type groupMessageRequest struct {
+	MsgKey             string `json:"msgKey"`
+	MsgParam           string `json:"msgParam"`
+	OpenConversationId string `json:"openConversationId"`
+	RobotCode          string `json:"robotCode"`
+}
+
+type groupMessageResponse struct {
+	ProcessQueryKey string `json:"processQueryKey"`
+}
+
+type otoMessageRequest struct {
+	MsgKey    string   `json:"msgKey"`
+	MsgParam  string   `json:"msgParam"`
+	UserIds   []string `json:"userIds"`
+	RobotCode string   `json:"robotCode"`
+}
+
+type webhookResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// openApi calls the OpenAPI on behalf of the app, the access token is attached
+// to every request and refreshed once if DingTalk rejects it
+type openApi struct {
+	http   *httpClient
+	tokens TokenSource
+}
+
+func (a *openApi) post(path string, body any, result any) error {
+	return authorized(a.tokens, func(accessToken string) error {
+		return a.http.post(path, body, &reqHeader{AccessToken: accessToken}, result)
+	})
+}
+
+func token(tokens TokenSource) (string, error) {
+	accessToken, err := tokens.Token()
 	if err != nil {
-		return
+		return "", fmt.Errorf("%w: %w", ErrTokenUnavailable, err)
 	}
-	respBodyMap := make(map[string]any)
-	err = json.Unmarshal(respBody, &respBodyMap)
+	return accessToken, nil
+}
+
+// authorized calls do with an access token from tokens, and calls it once
+// more with a refreshed token if DingTalk rejected the first one
+func authorized(tokens TokenSource, do func(accessToken string) error) error {
+	accessToken, err := token(tokens)
 	if err != nil {
-		return
+		return err
 	}
-	accessToken = respBodyMap["accessToken"].(string)
-	expireInSec = int(respBodyMap["expireIn"].(float64))
-	return
+	err = do(accessToken)
+	if !errors.Is(err, ErrInvalidToken) {
+		return err
+	}
+
+	invalidator, ok := tokens.(TokenInvalidator)
+	if !ok {
+		return err
+	}
+	invalidator.Invalidate(accessToken)
+	refreshed, tokenErr := token(tokens)
+	if tokenErr != nil {
+		return tokenErr
+	}
+	if refreshed == accessToken {
+		return err
+	}
+	logger.Warn("access token was rejected, retry with a refreshed one", "err", err)
+	return do(refreshed)
 }
 
-func sendMessage(accessToken string, msg Sendable) (processQueryKey string, err error) {
-	body := make(map[string]any)
-	// TODO: use more performance method
-	jsonBytes, err := json.Marshal(msg)
-	if err != nil {
-		return "", err
+func (h *httpClient) getAccessToken(clientId, clientSecret string) (accessToken string, expireInSec int, err error) {
+	body := &accessTokenRequest{
+		AppKey:    clientId,
+		AppSecret: clientSecret,
 	}
-	err = json.Unmarshal(jsonBytes, &body)
+	resp := &accessTokenResponse{}
+	err = h.post(openApiGetAccessToken, body, nil, resp)
 	if err != nil {
 		return
 	}
-	headers := &reqHeader{
-		AccessToken: accessToken,
+	if resp.AccessToken == "" {
+		return "", 0, errors.New("no access token in response")
 	}
-	respBody, err := post(openApiSendMessage, body, headers)
+	return resp.AccessToken, resp.ExpireIn, nil
+}
+
+func (a *openApi) sendMessage(msg Sendable) (processQueryKey string, err error) {
+	if webhook, ok := msg.(*WebhookMessage); ok {
+		return "", a.sendWebhookMessage(webhook)
+	}
+	path := openApiSendMessage
+	if routable, ok := msg.(interface{ openApiPath() string }); ok {
+		path = routable.openApiPath()
+	}
+	// msg encodes itself into the request body, see DingTalkMessage.MarshalJSON
+	resp := &groupMessageResponse{}
+	err = a.post(path, msg, resp)
 	if err != nil {
 		return
 	}
-	respBodyMap := make(map[string]string)
-	err = json.Unmarshal(respBody, &respBodyMap)
+	return resp.ProcessQueryKey, nil
+}
+
+func (a *openApi) sendWebhookMessage(msg *WebhookMessage) error {
+	resp := &webhookResponse{}
+	err := a.http.post(msg.webhook, msg, nil, resp)
 	if err != nil {
-		return
+		return err
+	}
+	if resp.ErrCode != 0 {
+		return &APIError{
+			StatusCode: http.StatusOK,
+			Code:       strconv.Itoa(resp.ErrCode),
+			Message:    resp.ErrMsg,
+		}
 	}
-	processQueryKey = respBodyMap["processQueryKey"]
-	return
+	return nil
 }
@@ -0,0 +1,120 @@
+package dingtalkbot
+
+var (
+	legacyApiGetUser            = "/topapi/v2/user/get"
+	legacyApiGetUserByUnionId   = "/topapi/user/getbyunionid"
+	legacyApiGetUserByMobile    = "/topapi/v2/user/getbymobile"
+	legacyApiListUsers          = "/topapi/v2/user/list"
+	legacyApiGetDepartment      = "/topapi/v2/department/get"
+	legacyApiListSubDepartments = "/topapi/v2/department/listsub"
+)
+
+const iContactsListPageSize = 100
+
+// User is a member of the organization
+type User struct {
+	UserId        string  `json:"userid"`
+	UnionId       string  `json:"unionid"`
+	Name          string  `json:"name"`
+	Avatar        string  `json:"avatar"`
+	Mobile        string  `json:"mobile"`
+	Email         string  `json:"email"`
+	Title         string  `json:"title"`
+	JobNumber     string  `json:"job_number"`
+	DeptIdList    []int64 `json:"dept_id_list"`
+	ManagerUserId string  `json:"manager_userid"`
+	Admin         bool    `json:"admin"`
+	Boss          bool    `json:"boss"`
+	Active        bool    `json:"active"`
+}
+
+// Department is a department of the organization, the root department id is 1
+type Department struct {
+	DeptId   int64  `json:"dept_id"`
+	Name     string `json:"name"`
+	ParentId int64  `json:"parent_id"`
+}
+
+type legacyUserIdResponse struct {
+	Result struct {
+		UserId string `json:"userid"`
+	} `json:"result"`
+}
+
+func (l *legacyApi) getUser(userId string) (*User, error) {
+	resp := &struct {
+		Result *User `json:"result"`
+	}{}
+	err := l.post(legacyApiGetUser, map[string]string{"userid": userId}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+func (l *legacyApi) getUserIdByUnionId(unionId string) (string, error) {
+	resp := &legacyUserIdResponse{}
+	err := l.post(legacyApiGetUserByUnionId, map[string]string{"unionid": unionId}, resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.Result.UserId, nil
+}
+
+func (l *legacyApi) getUserIdByMobile(mobile string) (string, error) {
+	resp := &legacyUserIdResponse{}
+	err := l.post(legacyApiGetUserByMobile, map[string]string{"mobile": mobile}, resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.Result.UserId, nil
+}
+
+func (l *legacyApi) listUsers(deptId int64) ([]*User, error) {
+	users := []*User{}
+	cursor := int64(0)
+	for {
+		resp := &struct {
+			Result struct {
+				HasMore    bool    `json:"has_more"`
+				NextCursor int64   `json:"next_cursor"`
+				List       []*User `json:"list"`
+			} `json:"result"`
+		}{}
+		err := l.post(legacyApiListUsers, map[string]int64{
+			"dept_id": deptId,
+			"cursor":  cursor,
+			"size":    int64(iContactsListPageSize),
+		}, resp)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, resp.Result.List...)
+		if !resp.Result.HasMore {
+			return users, nil
+		}
+		cursor = resp.Result.NextCursor
+	}
+}
+
+func (l *legacyApi) getDepartment(deptId int64) (*Department, error) {
+	resp := &struct {
+		Result *Department `json:"result"`
+	}{}
+	err := l.post(legacyApiGetDepartment, map[string]int64{"dept_id": deptId}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+func (l *legacyApi) listSubDepartments(deptId int64) ([]*Department, error) {
+	resp := &struct {
+		Result []*Department `json:"result"`
+	}{}
+	err := l.post(legacyApiListSubDepartments, map[string]int64{"dept_id": deptId}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
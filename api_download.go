@@ -0,0 +1,156 @@
+package dingtalkbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	openApiDownloadMessageFile = "/v1.0/robot/messageFiles/download"
+)
+
+var ErrDownloadTooLarge = errors.New("downloaded file exceeds the size limit")
+
+type messageFileDownloadRequest struct {
+	DownloadCode string `json:"downloadCode"`
+	RobotCode    string `json:"robotCode"`
+}
+
+type messageFileDownloadResponse struct {
+	DownloadUrl string `json:"downloadUrl"`
+}
+
+// Download is a file sent to the bot, Body must be closed by the caller
+type Download struct {
+	Body        io.ReadCloser
+	ContentType string
+	// Size is -1 if the server didn't tell it
+	Size int64
+}
+
+// limitedBody fails reading once more than limit bytes are read
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (n int, err error) {
+	if b.remaining < 0 {
+		return 0, ErrDownloadTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err = b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrDownloadTooLarge
+	}
+	return n, err
+}
+
+// idleBody cancels the download once no data arrives within timeout, unlike
+// http.Client.Timeout it doesn't limit how long the whole body takes
+type idleBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	if b.timer == nil {
+		return b.ReadCloser.Read(p)
+	}
+	b.timer.Reset(b.timeout)
+	defer b.timer.Stop()
+	return b.ReadCloser.Read(p)
+}
+
+func (b *idleBody) stop() {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.cancel()
+}
+
+func (b *idleBody) Close() error {
+	b.stop()
+	return b.ReadCloser.Close()
+}
+
+// getFile requests url with the http.Client of the api but without its
+// overall timeout, the timeout applies to each wait for data instead
+func (h *httpClient) getFile(url string) (*http.Response, error) {
+	hc := *h.client.GetClient()
+	hc.Timeout = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var timer *time.Timer
+	if h.timeout > 0 {
+		timer = time.AfterFunc(h.timeout, cancel)
+	}
+	body := &idleBody{timer: timer, timeout: h.timeout, cancel: cancel}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		body.stop()
+		return nil, err
+	}
+	if h.userAgent != "" {
+		req.Header.Set("User-Agent", h.userAgent)
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		body.stop()
+		return nil, err
+	}
+	// the timeout covers the headers, reads restart it
+	if timer != nil {
+		timer.Stop()
+	}
+	body.ReadCloser = resp.Body
+	resp.Body = body
+	return resp, nil
+}
+
+func (a *openApi) downloadMessageFile(downloadCode, robotCode string, limit int64) (*Download, error) {
+	body := &messageFileDownloadRequest{
+		DownloadCode: downloadCode,
+		RobotCode:    robotCode,
+	}
+	resp := &messageFileDownloadResponse{}
+	err := a.post(openApiDownloadMessageFile, body, resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.DownloadUrl == "" {
+		return nil, errors.New("no download url in response")
+	}
+
+	fileResp, err := a.http.getFile(resp.DownloadUrl)
+	if err != nil {
+		return nil, err
+	}
+	rawBody := fileResp.Body
+	if fileResp.StatusCode != http.StatusOK {
+		_ = rawBody.Close()
+		return nil, fmt.Errorf("download file failed, response status code: %d", fileResp.StatusCode)
+	}
+	size := fileResp.ContentLength
+	if limit > 0 && size > limit {
+		_ = rawBody.Close()
+		return nil, ErrDownloadTooLarge
+	}
+	if limit > 0 {
+		rawBody = &limitedBody{ReadCloser: rawBody, remaining: limit}
+	}
+	return &Download{
+		Body:        rawBody,
+		ContentType: fileResp.Header.Get("Content-Type"),
+		Size:        size,
+	}, nil
+}
@@ -0,0 +1,117 @@
+package dingtalkbot
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	legacyApiCreateSceneGroup        = "/topapi/im/chat/scenegroup/create"
+	legacyApiUpdateSceneGroup        = "/topapi/im/chat/scenegroup/update"
+	legacyApiGetSceneGroup           = "/topapi/im/chat/scenegroup/get"
+	legacyApiAddSceneGroupMembers    = "/topapi/im/chat/scenegroup/member/add"
+	legacyApiDeleteSceneGroupMembers = "/topapi/im/chat/scenegroup/member/delete"
+)
+
+// SceneGroupOptions describes a scene group to create from a template
+type SceneGroupOptions struct {
+	Title       string
+	TemplateId  string
+	OwnerUserId string
+	UserIds     []string
+	SubAdminIds []string
+	// UUID makes the creation idempotent, the same group is returned for the
+	// same UUID
+	UUID string
+}
+
+// SceneGroupUpdate changes a scene group, empty fields are left unchanged
+type SceneGroupUpdate struct {
+	Title       string
+	OwnerUserId string
+}
+
+// SceneGroup is a group created by the bot's app, OpenConversationId can be
+// used to send messages into it
+type SceneGroup struct {
+	OpenConversationId string `json:"open_conversation_id"`
+	ChatId             string `json:"chat_id"`
+	Title              string `json:"title"`
+	OwnerUserId        string `json:"owner_staff_id"`
+	TemplateId         string `json:"template_id"`
+	GroupUrl           string `json:"group_url"`
+}
+
+type createSceneGroupRequest struct {
+	Title       string `json:"title"`
+	TemplateId  string `json:"template_id"`
+	OwnerUserId string `json:"owner_user_id"`
+	UserIds     string `json:"user_ids,omitempty"`
+	SubAdminIds string `json:"subadmin_ids,omitempty"`
+	UUID        string `json:"uuid,omitempty"`
+}
+
+type updateSceneGroupRequest struct {
+	OpenConversationId string `json:"open_conversation_id"`
+	Title              string `json:"title,omitempty"`
+	OwnerUserId        string `json:"owner_user_id,omitempty"`
+}
+
+type sceneGroupMembersRequest struct {
+	OpenConversationId string `json:"open_conversation_id"`
+	UserIds            string `json:"user_ids"`
+}
+
+func (l *legacyApi) createSceneGroup(opts *SceneGroupOptions) (*SceneGroup, error) {
+	body := &createSceneGroupRequest{
+		Title:       opts.Title,
+		TemplateId:  opts.TemplateId,
+		OwnerUserId: opts.OwnerUserId,
+		UserIds:     strings.Join(opts.UserIds, ","),
+		SubAdminIds: strings.Join(opts.SubAdminIds, ","),
+		UUID:        opts.UUID,
+	}
+	resp := &struct {
+		Result *SceneGroup `json:"result"`
+	}{}
+	err := l.post(legacyApiCreateSceneGroup, body, resp)
+	if err != nil {
+		return nil, err
+	}
+	group := resp.Result
+	if group == nil || group.OpenConversationId == "" {
+		return nil, errors.New("no open conversation id in response")
+	}
+	group.Title = opts.Title
+	group.OwnerUserId = opts.OwnerUserId
+	group.TemplateId = opts.TemplateId
+	return group, nil
+}
+
+func (l *legacyApi) updateSceneGroup(openConversationId string, update *SceneGroupUpdate) error {
+	body := &updateSceneGroupRequest{
+		OpenConversationId: openConversationId,
+		Title:              update.Title,
+		OwnerUserId:        update.OwnerUserId,
+	}
+	return l.post(legacyApiUpdateSceneGroup, body, nil)
+}
+
+func (l *legacyApi) getSceneGroup(openConversationId string) (*SceneGroup, error) {
+	resp := &struct {
+		Result *SceneGroup `json:"result"`
+	}{}
+	err := l.post(legacyApiGetSceneGroup, map[string]string{"open_conversation_id": openConversationId}, resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Result, nil
+}
+
+func (l *legacyApi) sceneGroupMembers(path, openConversationId string, userIds []string) error {
+	body := &sceneGroupMembersRequest{
+		OpenConversationId: openConversationId,
+		UserIds:            strings.Join(userIds, ","),
+	}
+	return l.post(path, body, nil)
+}
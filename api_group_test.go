@@ -0,0 +1,38 @@
+package dingtalkbot
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreateSceneGroup(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "created", body: `{"errcode":0,"result":{"open_conversation_id":"cid-1","chat_id":"chat-1"}}`, want: "cid-1"},
+		{name: "no result", body: `{"errcode":0}`, wantErr: true},
+		{name: "no conversation id", body: `{"errcode":0,"result":{"chat_id":"chat-1"}}`, wantErr: true},
+		{name: "errcode", body: `{"errcode":4000,"errmsg":"invalid template"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legacy := &legacyApi{api: newStubApi(http.StatusOK, tt.body), baseUrl: legacyBaseUrl}
+			group, err := legacy.createSceneGroup(&SceneGroupOptions{Title: "oncall", TemplateId: "tpl", OwnerUserId: "owner"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("createSceneGroup = %+v, want error", group)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if group.OpenConversationId != tt.want || group.Title != "oncall" {
+				t.Errorf("createSceneGroup = %+v, want conversation %q", group, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package dingtalkbot
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const legacyBaseUrl = "https://oapi.dingtalk.com"
+
+var (
+	legacyApiSendWorkNotification = "/topapi/message/corpconversation/asyncsend_v2"
+)
+
+type legacyResponse struct {
+	ErrCode   int    `json:"errcode"`
+	ErrMsg    string `json:"errmsg"`
+	RequestId string `json:"request_id"`
+}
+
+type workNotificationRequest struct {
+	AgentId    string         `json:"agent_id"`
+	UseridList string         `json:"userid_list"`
+	Msg        map[string]any `json:"msg"`
+}
+
+type workNotificationResponse struct {
+	TaskId int64 `json:"task_id"`
+}
+
+// legacyApi calls the old oapi.dingtalk.com (topapi) endpoints, which take the
+// access token as query parameter and answer with an errcode/errmsg envelope
+type legacyApi struct {
+	api     *openApi
+	baseUrl string
+}
+
+func (l *legacyApi) post(path string, body any, result any) error {
+	return authorized(l.api.tokens, func(accessToken string) error {
+		req := l.api.http.request(nil).SetQueryParam("access_token", accessToken)
+		respBody, err := l.api.http.postRequest(req, l.baseUrl+path, body)
+		if err != nil {
+			return err
+		}
+		envelope := &legacyResponse{}
+		err = json.Unmarshal(respBody, envelope)
+		if err != nil {
+			return err
+		}
+		if envelope.ErrCode != 0 {
+			return &APIError{
+				StatusCode: http.StatusOK,
+				Code:       strconv.Itoa(envelope.ErrCode),
+				Message:    envelope.ErrMsg,
+				RequestId:  envelope.RequestId,
+			}
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(respBody, result)
+	})
+}
+
+func (l *legacyApi) sendWorkNotification(agentId string, userIds []string, msg map[string]any) (taskId int64, err error) {
+	body := &workNotificationRequest{
+		AgentId:    agentId,
+		UseridList: strings.Join(userIds, ","),
+		Msg:        msg,
+	}
+	resp := &workNotificationResponse{}
+	err = l.post(legacyApiSendWorkNotification, body, resp)
+	if err != nil {
+		return
+	}
+	return resp.TaskId, nil
+}
@@ -0,0 +1,106 @@
+package dingtalkbot
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type staticTokens string
+
+func (t staticTokens) Token() (string, error) {
+	return string(t), nil
+}
+
+// stubTransport answers every request with the same response, without any
+// network access
+type stubTransport struct {
+	status int
+	body   string
+}
+
+func (t *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+		_ = req.Body.Close()
+	}
+	return &http.Response{
+		StatusCode: t.status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func newStubApi(status int, respBody string) *openApi {
+	hc := newHttpClient()
+	hc.transport = &stubTransport{status: status, body: respBody}
+	hc.apply()
+	return &openApi{
+		http:   hc,
+		tokens: staticTokens("token"),
+	}
+}
+
+func BenchmarkSendMessageEncode(b *testing.B) {
+	api := newStubApi(http.StatusOK, `{"processQueryKey":"key"}`)
+	msg := newBenchmarkMessage()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := api.sendMessage(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestAuthorizedRetriesOnce(t *testing.T) {
+	tests := []struct {
+		name      string
+		custom    bool
+		rejects   int
+		wantSends []string
+		wantErr   error
+	}{
+		{name: "retry with a refreshed token", rejects: 1, wantSends: []string{"token-1", "token-2"}},
+		{name: "retry only once", rejects: 2, wantSends: []string{"token-1", "token-2"}, wantErr: ErrInvalidToken},
+		{name: "accepted token", rejects: 0, wantSends: []string{"token-1"}},
+		{name: "custom source can't be invalidated", custom: true, rejects: 1, wantSends: []string{"static"}, wantErr: ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued, sends := 0, []string{}
+			hc := newHttpClient()
+			hc.transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				if req.URL.Path == openApiGetAccessToken {
+					issued++
+					return respond(req, "application/json", []byte(fmt.Sprintf(`{"accessToken":"token-%d","expireIn":7200}`, issued))), nil
+				}
+				sends = append(sends, req.Header.Get("x-acs-dingtalk-access-token"))
+				if len(sends) <= tt.rejects {
+					resp := respond(req, "application/json", []byte(`{"code":"InvalidAuthentication","message":"token is invalid"}`))
+					resp.StatusCode = http.StatusUnauthorized
+					return resp, nil
+				}
+				return respond(req, "application/json", []byte(`{"processQueryKey":"key"}`)), nil
+			})
+			hc.apply()
+			api := &openApi{http: hc, tokens: newTokenSource(hc, "app-key", "app-secret")}
+			if tt.custom {
+				api.tokens = staticTokens("static")
+			}
+
+			_, err := api.sendMessage(newBenchmarkMessage())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("sendMessage = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(sends, tt.wantSends) {
+				t.Errorf("sent with tokens %q, want %q", sends, tt.wantSends)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package dingtalkbot
+
+import (
+	"unicode"
+)
+
+// closingQuotes maps the opening quotes to their closing ones, the Chinese
+// quotes are what IMEs usually type
+var closingQuotes = map[rune]rune{
+	'"':  '"',
+	'\'': '\'',
+	'“':  '”',
+	'‘':  '’',
+}
+
+// splitArgs splits s like a shell does: any whitespace (tabs, newlines and
+// full-width spaces included) separates arguments, quotes group them, and a
+// backslash escapes the next rune outside of single quotes. An unterminated
+// quote is taken literally, so apostrophes like in "don't" are kept.
+func splitArgs(s string) []string {
+	runes := []rune(s)
+	args := []string{}
+	arg := []rune{}
+	inArg := false
+	quote := rune(0)
+	escaped := false
+
+	// the state before the open quote, to re-scan it as a plain rune if it's
+	// never closed
+	quoteAt, quoteArgLen, quoteInArg := 0, 0, false
+	literalAt := -1
+
+	for i := 0; ; i++ {
+		if i == len(runes) {
+			if quote == 0 {
+				break
+			}
+			arg, inArg = arg[:quoteArgLen], quoteInArg
+			quote, escaped = 0, false
+			literalAt, i = quoteAt, quoteAt-1
+			continue
+		}
+		r := runes[i]
+		switch {
+		case escaped:
+			arg = append(arg, r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg = append(arg, r)
+			}
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, string(arg))
+				arg = arg[:0]
+				inArg = false
+			}
+		default:
+			if closing, ok := closingQuotes[r]; ok && i != literalAt {
+				quoteAt, quoteArgLen, quoteInArg = i, len(arg), inArg
+				quote, inArg = closing, true
+				continue
+			}
+			arg = append(arg, r)
+			inArg = true
+		}
+	}
+	if escaped {
+		arg = append(arg, '\\')
+	}
+	if inArg {
+		args = append(args, string(arg))
+	}
+	return args
+}
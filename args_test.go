@@ -0,0 +1,42 @@
+package dingtalkbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{``, []string{}},
+		{`   `, []string{}},
+		{`/deploy prod`, []string{"/deploy", "prod"}},
+		{"a\tb\nc　d", []string{"a", "b", "c", "d"}},
+		{`say "hello world"`, []string{"say", "hello world"}},
+		{`say 'hello world'`, []string{"say", "hello world"}},
+		{`say “你好 世界”`, []string{"say", "你好 世界"}},
+		{`say ‘你好 世界’`, []string{"say", "你好 世界"}},
+		{`a"b c"d`, []string{"ab cd"}},
+		{`""`, []string{""}},
+		{`a "" b`, []string{"a", "", "b"}},
+		{`a\ b`, []string{"a b"}},
+		{`"a \"b\" c"`, []string{`a "b" c`}},
+		{`'a \ b'`, []string{`a \ b`}},
+		{`"it's"`, []string{"it's"}},
+		{`tail\`, []string{`tail\`}},
+		{`/say don't do "a b"`, []string{"/say", "don't", "do", "a b"}},
+		{`/say "unterminated quote`, []string{"/say", `"unterminated`, "quote"}},
+		{`“left only`, []string{"“left", "only"}},
+		{`'a b' c'd`, []string{"a b", "c'd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := splitArgs(tt.in)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package dingtalkbot
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UsageError is returned by Context.Bind when the arguments don't fit the
+// declared struct, Usage describes what was expected
+type UsageError struct {
+	Err   error
+	Usage string
+}
+
+func (e *UsageError) Error() string {
+	return fmt.Sprintf("%s\n%s", e.Err, e.Usage)
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// bindField is a field of the bound struct which is declared by either an
+// `arg:"name"` (positional, in field order) or a `flag:"name,n"` tag, with
+// optional `default`, `required:"true"`, `enum:"a,b"` and `usage` tags
+type bindField struct {
+	index    int
+	name     string
+	short    string
+	flag     bool
+	def      string
+	hasDef   bool
+	required bool
+	enum     []string
+	usage    string
+}
+
+func (f *bindField) display() string {
+	switch {
+	case !f.flag:
+		return f.name
+	case f.short != "":
+		return fmt.Sprintf("-%s, --%s", f.short, f.name)
+	}
+	return "--" + f.name
+}
+
+func parseBindFields(t reflect.Type) ([]*bindField, error) {
+	fields := []*bindField{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		arg, isArg := sf.Tag.Lookup("arg")
+		flag, isFlag := sf.Tag.Lookup("flag")
+		if !isArg && !isFlag {
+			continue
+		}
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("field %s must be exported to be bound", sf.Name)
+		}
+		f := &bindField{index: i, flag: isFlag, usage: sf.Tag.Get("usage")}
+		if isFlag {
+			names := strings.Split(flag, ",")
+			f.name = names[0]
+			if len(names) > 1 {
+				f.short = names[1]
+			}
+		} else {
+			f.name = arg
+		}
+		if f.name == "" {
+			f.name = strings.ToLower(sf.Name)
+		}
+		f.def, f.hasDef = sf.Tag.Lookup("default")
+		f.required = sf.Tag.Get("required") == "true"
+		if enum := sf.Tag.Get("enum"); enum != "" {
+			f.enum = strings.Split(enum, ",")
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Usage describes the arguments of the struct v, as it's used by Bind
+func Usage(command string, v any) string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	fields, err := parseBindFields(t)
+	if err != nil {
+		return err.Error()
+	}
+	line := []string{"usage:"}
+	if command != "" {
+		line = append(line, command)
+	}
+	details := []string{}
+	for _, f := range fields {
+		name := f.name
+		if f.flag {
+			name = "--" + f.name
+			if t.Field(f.index).Type.Kind() != reflect.Bool {
+				name += "=<" + f.name + ">"
+			}
+		} else if t.Field(f.index).Type.Kind() == reflect.Slice {
+			name += "..."
+		}
+		if !f.required {
+			name = "[" + name + "]"
+		}
+		line = append(line, name)
+
+		detail := []string{}
+		if f.usage != "" {
+			detail = append(detail, f.usage)
+		}
+		if len(f.enum) > 0 {
+			detail = append(detail, "one of "+strings.Join(f.enum, "|"))
+		}
+		if f.hasDef {
+			detail = append(detail, "default "+f.def)
+		}
+		if len(detail) > 0 {
+			details = append(details, fmt.Sprintf("  %s: %s", f.display(), strings.Join(detail, ", ")))
+		}
+	}
+	return strings.Join(append([]string{strings.Join(line, " ")}, details...), "\n")
+}
+
+// Bind parses the arguments of the command into the struct pointed by v, see
+// bindField for the supported tags. A *UsageError is returned if the
+// arguments are invalid.
+func (c *Context) Bind(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("bind target must be a pointer to struct")
+	}
+	rv = rv.Elem()
+	fields, err := parseBindFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	err = bindArgs(rv, fields, c.args)
+	if err != nil {
+		return &UsageError{Err: err, Usage: Usage(c.command, v)}
+	}
+	return nil
+}
+
+func bindArgs(rv reflect.Value, fields []*bindField, args []string) error {
+	flags := make(map[string]*bindField)
+	positional := []*bindField{}
+	for _, f := range fields {
+		if !f.flag {
+			positional = append(positional, f)
+			continue
+		}
+		flags["--"+f.name] = f
+		if f.short != "" {
+			flags["-"+f.short] = f
+		}
+	}
+
+	seen := make(map[*bindField]bool)
+	set := func(f *bindField, value string) error {
+		if len(f.enum) > 0 && !contains(f.enum, value) {
+			return fmt.Errorf("%s must be one of %s", f.display(), strings.Join(f.enum, "|"))
+		}
+		err := setValue(rv.Field(f.index), value, seen[f])
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", f.display(), err)
+		}
+		seen[f] = true
+		return nil
+	}
+
+	onlyPositional := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !onlyPositional && arg == "--" {
+			onlyPositional = true
+			continue
+		}
+		if !onlyPositional && isFlag(arg) {
+			name, value, hasValue := strings.Cut(arg, "=")
+			f, ok := flags[name]
+			if !ok {
+				return fmt.Errorf("unknown flag %s", name)
+			}
+			if !hasValue {
+				if rv.Field(f.index).Kind() == reflect.Bool {
+					value = "true"
+				} else if i+1 < len(args) {
+					i++
+					value = args[i]
+				} else {
+					return fmt.Errorf("flag %s needs a value", name)
+				}
+			}
+			err := set(f, value)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if len(positional) == 0 {
+			return fmt.Errorf("unexpected argument %q", arg)
+		}
+		f := positional[0]
+		err := set(f, arg)
+		if err != nil {
+			return err
+		}
+		// a slice takes all the remaining arguments
+		if rv.Field(f.index).Kind() != reflect.Slice {
+			positional = positional[1:]
+		}
+	}
+
+	for _, f := range fields {
+		switch {
+		case seen[f]:
+		case f.required:
+			return fmt.Errorf("%s is required", f.display())
+		case f.hasDef:
+			err := setValue(rv.Field(f.index), f.def, false)
+			if err != nil {
+				return fmt.Errorf("invalid default of %s: %w", f.display(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// setValue converts value to the type of field, appending is for slices which
+// already got values
+func setValue(field reflect.Value, value string, appending bool) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	case reflect.Slice:
+		if !appending {
+			field.Set(reflect.MakeSlice(field.Type(), 0, 1))
+		}
+		elem := reflect.New(field.Type().Elem()).Elem()
+		err := setValue(elem, value, false)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.Append(field, elem))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// isFlag tells flags from positional arguments, negative numbers are the
+// latter
+func isFlag(arg string) bool {
+	if !strings.HasPrefix(arg, "-") || len(arg) == 1 {
+		return false
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err != nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind adapts a handler taking a struct of arguments into a HandlerFunc, the
+// usage is replied and the handler is skipped if the arguments are invalid
+func Bind[T any](handler func(ctx *Context, args *T)) HandlerFunc {
+	return func(ctx *Context) {
+		args := new(T)
+		err := ctx.Bind(args)
+		if err != nil {
+			ctx.Logger().Warn("failed to bind arguments", "err", err)
+			_ = ctx.Reply(err.Error())
+			return
+		}
+		handler(ctx, args)
+	}
+}
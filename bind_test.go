@@ -0,0 +1,107 @@
+package dingtalkbot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type deployArgs struct {
+	Service  string        `arg:"service" required:"true"`
+	Replicas int           `arg:"replicas" default:"1"`
+	Targets  []string      `arg:"targets"`
+	Env      string        `flag:"env,e" enum:"dev,prod" default:"dev" usage:"target environment"`
+	Force    bool          `flag:"force,f"`
+	Timeout  time.Duration `flag:"timeout" default:"30s"`
+	Offset   int           `flag:"offset"`
+	Tags     []string      `flag:"tag,t"`
+}
+
+func TestBindArgs(t *testing.T) {
+	defaults := deployArgs{Service: "api", Replicas: 1, Env: "dev", Timeout: 30 * time.Second}
+	with := func(f func(*deployArgs)) deployArgs {
+		args := defaults
+		f(&args)
+		return args
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    deployArgs
+		wantErr bool
+	}{
+		{name: "defaults", args: []string{"api"}, want: defaults},
+		{name: "positionals", args: []string{"api", "3"}, want: with(func(a *deployArgs) { a.Replicas = 3 })},
+		{name: "slice positional takes the rest", args: []string{"api", "3", "a", "b"}, want: with(func(a *deployArgs) {
+			a.Replicas, a.Targets = 3, []string{"a", "b"}
+		})},
+		{name: "flags between slice positionals", args: []string{"api", "3", "a", "--force", "b"}, want: with(func(a *deployArgs) {
+			a.Replicas, a.Targets, a.Force = 3, []string{"a", "b"}, true
+		})},
+		{name: "negative number is positional", args: []string{"api", "-3"}, want: with(func(a *deployArgs) { a.Replicas = -3 })},
+		{name: "negative flag value", args: []string{"api", "--offset", "-5"}, want: with(func(a *deployArgs) { a.Offset = -5 })},
+		{name: "flag value after equals", args: []string{"api", "--offset=-5"}, want: with(func(a *deployArgs) { a.Offset = -5 })},
+		{name: "short flag before positionals", args: []string{"-e", "prod", "api"}, want: with(func(a *deployArgs) { a.Env = "prod" })},
+		{name: "bool flag", args: []string{"api", "-f"}, want: with(func(a *deployArgs) { a.Force = true })},
+		{name: "bool flag with value", args: []string{"api", "--force=false"}, want: defaults},
+		{name: "duration flag", args: []string{"api", "--timeout", "2m"}, want: with(func(a *deployArgs) { a.Timeout = 2 * time.Minute })},
+		{name: "repeated slice flag", args: []string{"--tag", "a", "api", "-t", "b"}, want: with(func(a *deployArgs) { a.Tags = []string{"a", "b"} })},
+		{name: "double dash ends flags", args: []string{"--", "-api"}, want: with(func(a *deployArgs) { a.Service = "-api" })},
+		{name: "missing required", args: []string{}, wantErr: true},
+		{name: "invalid int", args: []string{"api", "x"}, wantErr: true},
+		{name: "enum mismatch", args: []string{"api", "--env=staging"}, wantErr: true},
+		{name: "unknown flag", args: []string{"api", "--unknown"}, wantErr: true},
+		{name: "flag without value", args: []string{"api", "--offset"}, wantErr: true},
+		{name: "invalid duration", args: []string{"api", "--timeout", "soon"}, wantErr: true},
+	}
+	fields, err := parseBindFields(reflect.TypeOf(deployArgs{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := deployArgs{}
+			err := bindArgs(reflect.ValueOf(&got).Elem(), fields, tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("bindArgs(%q) = %+v, want error", tt.args, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bindArgs(%q): %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bindArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFlag(t *testing.T) {
+	tests := map[string]bool{
+		"-f":      true,
+		"--force": true,
+		"-e=prod": true,
+		"-":       false,
+		"-3":      false,
+		"-1.5":    false,
+		"-1e3":    false,
+		"api":     false,
+	}
+	for arg, want := range tests {
+		if got := isFlag(arg); got != want {
+			t.Errorf("isFlag(%q) = %v, want %v", arg, got, want)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	want := "usage: /deploy service [replicas] [targets...] [--env=<env>] [--force] [--timeout=<timeout>] [--offset=<offset>] [--tag=<tag>]\n" +
+		"  replicas: default 1\n" +
+		"  -e, --env: target environment, one of dev|prod, default dev\n" +
+		"  --timeout: default 30s"
+	if got := Usage("/deploy", &deployArgs{}); got != want {
+		t.Errorf("Usage = %q, want %q", got, want)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
 	"time"
 
 	"github.com/charmbracelet/log"
@@ -25,10 +26,12 @@ type Client struct {
 
 	dClient *dingClient.StreamClient
 
-	modules *RWMap[MessageType, Module]
+	modules  *RWMap[MessageType, []Module]
+	dispatch DispatchMode
 
-	cache     *badger.DB
+	cache *badger.DB
 
+	ctx       context.Context
 	cancel    context.CancelFunc
 	destroyed bool
 }
@@ -37,7 +40,7 @@ func NewClient(id, secret string) (client *Client, err error) {
 	client = (&Client{
 		clientId:     id,
 		clientSecret: secret,
-		modules:      NewRWMap[MessageType, Module](),
+		modules:      NewRWMap[MessageType, []Module](),
 	}).Debug(false)
 
 	err = client.initCache()
@@ -46,12 +49,25 @@ func NewClient(id, secret string) (client *Client, err error) {
 	}
 
 	// init messenger
+	hc := newHttpClient()
+	api := &openApi{
+		http:   hc,
+		tokens: newTokenSource(hc, id, secret),
+	}
 	client.Messenger = &Messenger{
+		api: api,
+		legacy: &legacyApi{
+			api:     api,
+			baseUrl: legacyBaseUrl,
+		},
 		cache:       client.cache,
 		mqm:         NewRWMap[string, *queue.Queue[Sendable]](),
 		mq:          make(chan Sendable, 10),
 		storage:     make(map[string]string),
-		tokenExpiry: time.Now(),
+		dedupTTL:    iDefaultDedupTTL,
+		contactsTTL: iDefaultContactsTTL,
+
+		downloadLimit: iDefaultDownloadLimit,
 	}
 	func(storage map[string]string) {
 		storage["clientId"] = id
@@ -87,17 +103,25 @@ func (client *Client) initCache() (err error) {
 }
 
 func (c *Client) onMessage(message *Message) error {
-	module, ok := c.modules.Get(message.Type)
+	modules, ok := c.modules.Get(message.Type)
 	// if message type never registered
 	if !ok {
 		return nil
 	}
-	ctx := module.parseContext(message)
-	if ctx == nil {
-		return nil
+	errs := []error{}
+	for _, module := range modules {
+		ctx := module.parseContext(message)
+		// the module doesn't handle this message
+		if ctx == nil || ctx.handler == nil {
+			continue
+		}
+		ctx.Client = c
+		errs = append(errs, ctx.handling())
+		if c.dispatch == DispatchFirstMatch {
+			break
+		}
 	}
-	ctx.Client = c
-	return ctx.handling()
+	return errors.Join(errs...)
 }
 
 func (c *Client) onEventReceived(ctx context.Context, header *event.EventHeader, rawData []byte) (_ event.EventProcessStatusType, err error) {
@@ -157,7 +181,7 @@ func (c *Client) Start() error {
 	dingLogger.SetLogger(&iLogger{})
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
-	c.cancel = cancelFunc
+	c.ctx, c.cancel = ctx, cancelFunc
 
 	c.Messenger.start(ctx)
 
@@ -177,6 +201,14 @@ func (c *Client) Start() error {
 	return nil
 }
 
+// lifecycle returns the context which is cancelled by Stop
+func (c *Client) lifecycle() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 func (c *Client) Stop() error {
 	if c.cancel == nil {
 		return errors.New("can't stop a never started bot")
@@ -186,7 +218,116 @@ func (c *Client) Stop() error {
 	return nil
 }
 
+// UseOutbound adds a middleware which runs on every Sendable before queueing
+func (c *Client) UseOutbound(outbound OutboundFunc) *Client {
+	c.Messenger.useOutbound(outbound)
+	return c
+}
+
+// TokenSource replaces the default access token source
+func (c *Client) TokenSource(tokens TokenSource) *Client {
+	c.Messenger.api.tokens = tokens
+	return c
+}
+
+// TokenStore lets the default access token source share its token through
+// store, e.g. between processes which use the same app credential
+func (c *Client) TokenStore(store TokenStore) *Client {
+	if tokens, ok := c.Messenger.api.tokens.(*appTokenSource); ok {
+		tokens.setStore(store)
+	} else {
+		logger.Warn("token store is ignored by a custom token source")
+	}
+	return c
+}
+
+// BaseURL points OpenAPI requests to another host, e.g. a local stand-in
+func (c *Client) BaseURL(url string) *Client {
+	c.Messenger.api.http.baseUrl = url
+	c.Messenger.api.http.apply()
+	return c
+}
+
+// LegacyBaseURL points legacy (oapi.dingtalk.com) requests to another host
+func (c *Client) LegacyBaseURL(url string) *Client {
+	c.Messenger.legacy.baseUrl = url
+	return c
+}
+
+// AgentId sets the agent id of the app, which is required by work notifications
+func (c *Client) AgentId(agentId string) *Client {
+	c.Messenger.storage["agentId"] = agentId
+	return c
+}
+
+// HTTPClient replaces the underlying http.Client with a copy of hc, settings
+// made by the other HTTP options are kept and hc itself isn't changed. The
+// timeout of hc is used unless Timeout was called.
+func (c *Client) HTTPClient(hc *http.Client) *Client {
+	c.Messenger.api.http.setClient(hc)
+	return c
+}
+
+// Transport replaces the transport of the underlying http.Client
+func (c *Client) Transport(transport http.RoundTripper) *Client {
+	c.Messenger.api.http.transport = transport
+	c.Messenger.api.http.apply()
+	return c
+}
+
+// Proxy sends OpenAPI requests through the proxy of url, it only works with
+// an *http.Transport, the proxy is ignored with an error logged otherwise
+func (c *Client) Proxy(url string) *Client {
+	c.Messenger.api.http.proxy = url
+	c.Messenger.api.http.apply()
+	return c
+}
+
+// Timeout sets the timeout of every OpenAPI request
+func (c *Client) Timeout(timeout time.Duration) *Client {
+	c.Messenger.api.http.timeout = timeout
+	c.Messenger.api.http.timeoutSet = true
+	c.Messenger.api.http.apply()
+	return c
+}
+
+// UserAgent sets the User-Agent header of every OpenAPI request
+func (c *Client) UserAgent(userAgent string) *Client {
+	c.Messenger.api.http.userAgent = userAgent
+	c.Messenger.api.http.apply()
+	return c
+}
+
+// DedupTTL sets how long an idempotency key suppresses duplicated messages
+func (c *Client) DedupTTL(ttl time.Duration) *Client {
+	c.Messenger.dedupTTL = ttl
+	return c
+}
+
+// ContactsTTL sets how long user and department lookups are cached, zero
+// or less disables the cache
+func (c *Client) ContactsTTL(ttl time.Duration) *Client {
+	c.Messenger.contactsTTL = ttl
+	return c
+}
+
+// DownloadLimit sets the max size in bytes of downloaded files, 20 MiB by
+// default, zero means no limit
+func (c *Client) DownloadLimit(limit int64) *Client {
+	c.Messenger.downloadLimit = limit
+	return c
+}
+
+// Register appends module to the modules of messageType, they are tried in
+// the order they are registered, see Dispatch
 func (c *Client) Register(messageType MessageType, module Module) *Client {
-	c.modules.Put(messageType, module)
+	modules, _ := c.modules.Get(messageType)
+	c.modules.Put(messageType, append(modules, module))
 	return c
-}
\ No newline at end of file
+}
+
+// Dispatch sets how a message is dispatched to the modules of its type
+func (c *Client) Dispatch(mode DispatchMode) *Client {
+	c.dispatch = mode
+	return c
+}
@@ -0,0 +1,88 @@
+package dingtalkbot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/open-dingtalk/dingtalk-stream-sdk-go/chatbot"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	client, err := NewClient("robot", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.cache.Close() })
+	return client
+}
+
+// groupChat is a chat message sent into the group "cid" by the staff "staff"
+func groupChat(text string) *chatbot.BotCallbackDataModel {
+	chat := &chatbot.BotCallbackDataModel{
+		ConversationId:   "cid",
+		ConversationType: ConversationGroup,
+		SenderStaffId:    "staff",
+		Msgtype:          "text",
+	}
+	chat.Text.Content = text
+	return chat
+}
+
+// queued drains the messages queued for conversationId
+func queued(client *Client, conversationId string) []Sendable {
+	q, ok := client.Messenger.mqm.Get(conversationId)
+	if !ok {
+		return nil
+	}
+	msgs := []Sendable{}
+	for !q.Empty() {
+		msgs = append(msgs, q.Dequeue())
+	}
+	return msgs
+}
+
+func TestDispatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    DispatchMode
+		text    string
+		trace   []string
+		wantErr bool
+	}{
+		{name: "first match", mode: DispatchFirstMatch, text: "/cmd", trace: []string{"cmd"}},
+		{name: "first match skips modules without handler", mode: DispatchFirstMatch, text: "hello", trace: []string{"simple"}},
+		{name: "fan out", mode: DispatchFanOut, text: "/cmd", trace: []string{"cmd", "simple", "panic"}, wantErr: true},
+		{name: "fan out skips modules without handler", mode: DispatchFanOut, text: "hello", trace: []string{"simple", "panic"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &tracer{}
+			client := newTestClient(t).Dispatch(tt.mode)
+			client.Register(TypeChat, ModuleChatChain().Handle("cmd", tr.handler("cmd")))
+			client.Register(TypeChat, ModuleSimple().Handle(tr.handler("simple")))
+			client.Register(TypeChat, ModuleSimple().Handle(func(ctx *Context) {
+				tr.handler("panic")(ctx)
+				panic("boom")
+			}))
+
+			err := client.onMessage(toMessage(ChatMessage(groupChat(tt.text))))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("onMessage = %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual([]string(*tr), tt.trace) {
+				t.Errorf("trace = %q, want %q", *tr, tt.trace)
+			}
+		})
+	}
+}
+
+func TestDispatchUnregisteredType(t *testing.T) {
+	client := newTestClient(t)
+	client.Register(TypeChat, ModuleSimple().Handle(func(ctx *Context) {
+		t.Error("chat module handled an event")
+	}))
+	if err := client.onMessage(&Message{Type: TypeEvent}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,65 +1,163 @@
 package dingtalkbot
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/charmbracelet/log"
 )
 
+var ErrNoDownloadCode = errors.New("message has no file to download")
+
 type HandlerFunc func(*Context)
 
+var _ context.Context = (*Context)(nil)
+
+// Context is the context.Context of a handler too, it's cancelled by
+// Client.Stop or when the timeout of the module or command expires
 type Context struct {
 	*Message
 
 	Client *Client
 
+	ctx     context.Context
+	timeout handlerTimeout
+
 	index       int
 	middlewares []HandlerFunc
 	handler     HandlerFunc
+	// handlers are the middlewares followed by the handler
+	handlers []HandlerFunc
+	err      error
+
+	text    string
+	command string
+	args    []string
+	rawArgs string
+	params  map[string]string
+}
 
-	args []string
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	if c.ctx == nil {
+		return
+	}
+	return c.ctx.Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Done()
+}
+
+func (c *Context) Err() error {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
+func (c *Context) Value(key any) any {
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Value(key)
+}
 
-	Next func()
+// abortIndex is beyond any chain of handlers
+const abortIndex = math.MaxInt / 2
+
+// Next runs the remaining handlers of the chain, a middleware can do work
+// after it returns, e.g. timing or cleanup. Calling Next is optional, the
+// chain goes on after a middleware returns without calling it, so a guard
+// which rejects a message must call Abort instead of just returning
+func (c *Context) Next() {
+	c.index++
+	for c.index < len(c.handlers) {
+		c.handlers[c.index](c)
+		c.index++
+	}
 }
 
+// Abort prevents the remaining handlers from running, the handlers which
+// already called Next still finish their work after it. It's the only way to
+// stop the chain, returning from a middleware doesn't
 func (c *Context) Abort() {
-	c.AbortWithError(nil)
+	c.index = abortIndex
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
 }
 
+// AbortWithError aborts and records err, which is returned to the client as
+// the result of handling
 func (c *Context) AbortWithError(err error) {
-	panic(err)
+	c.err = err
+	c.Abort()
+}
+
+// AbortError returns the error recorded by AbortWithError
+func (c *Context) AbortError() error {
+	return c.err
+}
+
+// Command returns the command which the chat message was routed by, it's
+// empty if no command matched
+func (c *Context) Command() string {
+	return c.command
 }
 
 func (c *Context) Args() []string {
 	return c.args
 }
 
-func (c *Context) dealWithMiddlewares() {
-	c.Next = func() {
-		c.index++
-		if c.middlewares == nil {
-			return
-		}
-		if c.index < len(c.middlewares) {
-			c.middlewares[c.index](c)
-		}
+// RawArgs returns the text after the command as it was typed, without the
+// surrounding whitespace
+func (c *Context) RawArgs() string {
+	return c.rawArgs
+}
+
+// Sender looks up the user who sent the chat message, the result is cached
+func (c *Context) Sender() (*User, error) {
+	if c.Message.Type != TypeChat || c.Message.Chat().SenderStaffId == "" {
+		return nil, ErrUserNotFound
 	}
-	c.index = -1
-	c.Next()
+	return c.Client.GetUser(c.Message.Chat().SenderStaffId)
 }
 
-func (c *Context) handling() (err error) {
-	defer func() {
-		if e := recover(); e != nil {
-			switch e := e.(type) {
-			case error:
-				err = e
-			default:
-				err = fmt.Errorf("%v", e)
-			}
-		}
-	}()
+// Download fetches the file of a picture, file, audio or video chat message
+func (c *Context) Download() (*Download, error) {
+	if c.Message.Type != TypeChat {
+		return nil, ErrNoDownloadCode
+	}
+	content, ok := c.Message.Chat().Content.(map[string]any)
+	if !ok {
+		return nil, ErrNoDownloadCode
+	}
+	downloadCode, ok := content["downloadCode"].(string)
+	if !ok || downloadCode == "" {
+		return nil, ErrNoDownloadCode
+	}
+	return c.Client.DownloadFile(downloadCode)
+}
+
+// Param returns a named capture of the pattern route which handles the
+// message, it's empty if there's no such capture
+func (c *Context) Param(name string) string {
+	return c.params[name]
+}
 
+// Params returns all the named captures of the pattern route
+func (c *Context) Params() map[string]string {
+	return c.params
+}
+
+func (c *Context) handling() error {
 	if c.handler == nil {
 		return nil
 	}
@@ -74,13 +172,52 @@ func (c *Context) handling() (err error) {
 		return "unknown"
 	}()))
 
-	c.dealWithMiddlewares()
-	// if all middlewares are called
-	if c.index == len(c.middlewares) {
-		c.handler(c)
+	var cancel context.CancelFunc
+	if c.timeout.timeout > 0 {
+		c.ctx, cancel = context.WithTimeout(c.Client.lifecycle(), c.timeout.timeout)
+	} else {
+		c.ctx, cancel = context.WithCancel(c.Client.lifecycle())
+	}
+	defer cancel()
+
+	if c.timeout.timeout <= 0 {
+		return c.run()
 	}
+	// the handler can't be killed, it keeps running after the timeout, but
+	// sees its Context cancelled
+	done := make(chan error, 1)
+	go func() {
+		done <- c.run()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-c.ctx.Done():
+		if errors.Is(c.ctx.Err(), context.DeadlineExceeded) && c.timeout.reply != "" {
+			_ = c.Reply(c.timeout.reply)
+		}
+		return fmt.Errorf("handler was cancelled: %w", c.ctx.Err())
+	}
+}
+
+// run calls the chain of handlers, a panic is returned as error
+func (c *Context) run() (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			switch e := e.(type) {
+			case error:
+				err = fmt.Errorf("handler panicked: %w", e)
+			default:
+				err = fmt.Errorf("handler panicked: %v", e)
+			}
+		}
+	}()
+
+	c.handlers = append(append(make([]HandlerFunc, 0, len(c.middlewares)+1), c.middlewares...), c.handler)
+	c.index = -1
+	c.Next()
 
-	return nil
+	return c.err
 }
 
 func (c *Context) Logger() *log.Logger {
@@ -0,0 +1,128 @@
+package dingtalkbot
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContextChain(t *testing.T) {
+	tests := []struct {
+		name        string
+		middlewares func(trace *[]string) []HandlerFunc
+		want        []string
+	}{
+		{
+			name: "next wraps the handler",
+			middlewares: func(trace *[]string) []HandlerFunc {
+				return []HandlerFunc{func(c *Context) {
+					*trace = append(*trace, "before")
+					c.Next()
+					*trace = append(*trace, "after")
+				}}
+			},
+			want: []string{"before", "handler", "after"},
+		},
+		{
+			name: "returning without next goes on",
+			middlewares: func(trace *[]string) []HandlerFunc {
+				return []HandlerFunc{func(c *Context) {
+					*trace = append(*trace, "guard")
+				}}
+			},
+			want: []string{"guard", "handler"},
+		},
+		{
+			name: "abort stops the chain",
+			middlewares: func(trace *[]string) []HandlerFunc {
+				return []HandlerFunc{
+					func(c *Context) {
+						*trace = append(*trace, "guard")
+						c.Abort()
+					},
+					func(c *Context) {
+						*trace = append(*trace, "unreachable")
+					},
+				}
+			},
+			want: []string{"guard"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trace []string
+			c := &Context{
+				middlewares: tt.middlewares(&trace),
+				handler: func(c *Context) {
+					trace = append(trace, "handler")
+				},
+			}
+			if err := c.run(); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(trace, tt.want) {
+				t.Errorf("trace = %v, want %v", trace, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandlingTimeout(t *testing.T) {
+	client := newTestClient(t)
+	handled := make(chan error, 1)
+	client.Register(TypeChat, ModuleChatChain().Handle("slow", func(ctx *Context) {
+		<-ctx.Done()
+		handled <- ctx.Err()
+	}, WithTimeout(20*time.Millisecond, "too slow")))
+
+	err := client.onMessage(toMessage(ChatMessage(groupChat("/slow"))))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("onMessage = %v, want DeadlineExceeded", err)
+	}
+	if err = <-handled; !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("handler saw %v, want DeadlineExceeded", err)
+	}
+	msgs := queued(client, "cid")
+	if len(msgs) != 1 {
+		t.Fatalf("queued %d messages, want the timeout reply", len(msgs))
+	}
+	if msg := msgs[0].(*DingTalkMessage); msg.MsgParam["content"] != "too slow" {
+		t.Errorf("reply = %v, want too slow", msg.MsgParam)
+	}
+}
+
+func TestHandlingCancelledByStop(t *testing.T) {
+	client := newTestClient(t)
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+	started := make(chan struct{})
+	client.Register(TypeChat, ModuleChatChain().Handle("slow", func(ctx *Context) {
+		close(started)
+		<-ctx.Done()
+	}, WithTimeout(time.Minute, "too slow")))
+
+	go func() {
+		<-started
+		_ = client.Stop()
+	}()
+	err := client.onMessage(toMessage(ChatMessage(groupChat("/slow"))))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("onMessage = %v, want Canceled", err)
+	}
+	if msgs := queued(client, "cid"); len(msgs) != 0 {
+		t.Errorf("queued %d messages, want no timeout reply", len(msgs))
+	}
+}
+
+func TestHandlingWithoutTimeout(t *testing.T) {
+	client := newTestClient(t)
+	client.Register(TypeChat, ModuleChatChain().Handle("fast", func(ctx *Context) {
+		if _, ok := ctx.Deadline(); ok {
+			t.Error("handler has a deadline, want none")
+		}
+	}))
+	if err := client.onMessage(toMessage(ChatMessage(groupChat("/fast")))); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package dingtalkbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrThrottled    = errors.New("request was throttled by dingtalk")
+	ErrInvalidToken = errors.New("access token is invalid or expired")
+	ErrNoPermission = errors.New("no permission to call the dingtalk api")
+
+	ErrTokenUnavailable = errors.New("access token is unavailable")
+)
+
+// APIError is returned when DingTalk rejects an OpenAPI request, it matches
+// ErrThrottled, ErrInvalidToken and ErrNoPermission with errors.Is
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	RequestId  string `json:"requestid"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("dingtalk api error: status=%d, code=%s, message=%s, requestid=%s",
+		e.StatusCode, e.Code, e.Message, e.RequestId)
+}
+
+// legacyErrCodes maps the errcode of oapi.dingtalk.com to sentinel errors
+var legacyErrCodes = map[string]error{
+	"40001": ErrInvalidToken,
+	"40014": ErrInvalidToken,
+	"42001": ErrInvalidToken,
+	"90002": ErrThrottled,
+	"90006": ErrThrottled,
+	"90018": ErrThrottled,
+	"60011": ErrNoPermission,
+	"60020": ErrNoPermission,
+}
+
+func (e *APIError) Is(target error) bool {
+	if legacy, ok := legacyErrCodes[e.Code]; ok {
+		return legacy == target
+	}
+	// QPS limits come as 403 Forbidden.AccessDenied.QpsLimitForApi and alike
+	qpsLimit := strings.Contains(e.Code, "QpsLimit")
+	switch target {
+	case ErrThrottled:
+		return e.StatusCode == http.StatusTooManyRequests ||
+			strings.HasPrefix(e.Code, "Throttling") || qpsLimit
+	case ErrInvalidToken:
+		return e.StatusCode == http.StatusUnauthorized ||
+			strings.EqualFold(e.Code, "InvalidAuthentication")
+	case ErrNoPermission:
+		return !qpsLimit && (e.StatusCode == http.StatusForbidden ||
+			strings.HasPrefix(e.Code, "Forbidden"))
+	}
+	return false
+}
+
+// newAPIError decodes the error body of a failed response, the raw body is
+// kept as message if it isn't the expected json
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{}
+	err := json.Unmarshal(body, apiErr)
+	if err != nil || (apiErr.Code == "" && apiErr.Message == "") {
+		apiErr = &APIError{Message: string(body)}
+	}
+	apiErr.StatusCode = statusCode
+	return apiErr
+}
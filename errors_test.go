@@ -0,0 +1,81 @@
+package dingtalkbot
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		want       APIError
+	}{
+		{
+			name:       "json",
+			statusCode: http.StatusBadRequest,
+			body:       `{"code":"invalidParameter","message":"bad robotCode","requestid":"req-1"}`,
+			want:       APIError{StatusCode: http.StatusBadRequest, Code: "invalidParameter", Message: "bad robotCode", RequestId: "req-1"},
+		},
+		{
+			name:       "not json",
+			statusCode: http.StatusBadGateway,
+			body:       `<html>502 Bad Gateway</html>`,
+			want:       APIError{StatusCode: http.StatusBadGateway, Message: `<html>502 Bad Gateway</html>`},
+		},
+		{
+			name:       "json without code",
+			statusCode: http.StatusInternalServerError,
+			body:       `{"error":"oops"}`,
+			want:       APIError{StatusCode: http.StatusInternalServerError, Message: `{"error":"oops"}`},
+		},
+		{
+			name:       "empty",
+			statusCode: http.StatusServiceUnavailable,
+			body:       ``,
+			want:       APIError{StatusCode: http.StatusServiceUnavailable},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newAPIError(tt.statusCode, []byte(tt.body)); *got != tt.want {
+				t.Errorf("newAPIError = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		code       string
+		want       error
+	}{
+		{"too many requests", http.StatusTooManyRequests, "", ErrThrottled},
+		{"throttling code", http.StatusBadRequest, "Throttling.Api", ErrThrottled},
+		{"qps limit", http.StatusForbidden, "Forbidden.AccessDenied.QpsLimitForApi", ErrThrottled},
+		{"qps limit of app", http.StatusForbidden, "Forbidden.AccessDenied.QpsLimitForAppkeyAndApi", ErrThrottled},
+		{"unauthorized", http.StatusUnauthorized, "", ErrInvalidToken},
+		{"invalid authentication", http.StatusBadRequest, "InvalidAuthentication", ErrInvalidToken},
+		{"forbidden", http.StatusForbidden, "", ErrNoPermission},
+		{"forbidden code", http.StatusBadRequest, "Forbidden.AccessDenied.AccessTokenPermissionDenied", ErrNoPermission},
+		{"legacy invalid token", http.StatusOK, "40014", ErrInvalidToken},
+		{"legacy throttled", http.StatusOK, "90018", ErrThrottled},
+		{"legacy no permission", http.StatusOK, "60011", ErrNoPermission},
+		{"other", http.StatusBadRequest, "invalidParameter", nil},
+	}
+	sentinels := []error{ErrThrottled, ErrInvalidToken, ErrNoPermission}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := error(&APIError{StatusCode: tt.statusCode, Code: tt.code})
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%v, %v) = %v", err, sentinel, got)
+				}
+			}
+		})
+	}
+}
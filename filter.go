@@ -0,0 +1,87 @@
+package dingtalkbot
+
+const (
+	ConversationSingle = "1"
+	ConversationGroup  = "2"
+)
+
+// Filter decides whether a message may be handled by a command or module
+type Filter func(ctx *Context) bool
+
+func chatFilter(f func(chat ChatMessage) bool) Filter {
+	return func(ctx *Context) bool {
+		return ctx.Message.Type == TypeChat && f(ctx.Message.Chat())
+	}
+}
+
+// InConversationType allows ConversationSingle (1:1) or ConversationGroup chats
+func InConversationType(types ...string) Filter {
+	return chatFilter(func(chat ChatMessage) bool {
+		return contains(types, chat.ConversationType)
+	})
+}
+
+// InConversations allows the chats of the conversation ids
+func InConversations(conversationIds ...string) Filter {
+	return chatFilter(func(chat ChatMessage) bool {
+		return contains(conversationIds, chat.ConversationId)
+	})
+}
+
+// FromSenders allows the senders of the staff ids
+func FromSenders(staffIds ...string) Filter {
+	return chatFilter(func(chat ChatMessage) bool {
+		return contains(staffIds, chat.SenderStaffId)
+	})
+}
+
+// AdminOnly allows the admins of the organization
+func AdminOnly() Filter {
+	return chatFilter(func(chat ChatMessage) bool {
+		return chat.IsAdmin
+	})
+}
+
+func passFilters(ctx *Context, filters []Filter) bool {
+	for _, filter := range filters {
+		if !filter(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// rejectHandler replies text to a message which didn't pass the filters
+func rejectHandler(text string) HandlerFunc {
+	return func(ctx *Context) {
+		_ = ctx.Reply(text)
+	}
+}
+
+// WithFilters handles the command only if all filters pass, otherwise the
+// message is skipped, or rejected by the reply of WithRejectReply
+func WithFilters(filters ...Filter) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.filters = append(cmd.filters, filters...)
+	}
+}
+
+// WithRejectReply replies text to messages which the filters don't allow
+func WithRejectReply(text string) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.rejectReply = text
+	}
+}
+
+// Filter handles messages only if all filters pass, otherwise they are
+// skipped, or rejected by the reply of RejectReply
+func (c *Chain) Filter(filters ...Filter) *Chain {
+	c.filters = append(c.filters, filters...)
+	return c
+}
+
+// RejectReply replies text to messages which the filters don't allow
+func (c *Chain) RejectReply(text string) *Chain {
+	c.rejectReply = text
+	return c
+}
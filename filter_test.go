@@ -0,0 +1,115 @@
+package dingtalkbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	chat := groupChat("/ping")
+	chat.IsAdmin = true
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"group type", InConversationType(ConversationGroup), true},
+		{"single type", InConversationType(ConversationSingle), false},
+		{"conversation", InConversations("other", "cid"), true},
+		{"other conversation", InConversations("other"), false},
+		{"sender", FromSenders("staff"), true},
+		{"other sender", FromSenders("ops"), false},
+		{"admin", AdminOnly(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(&Context{Message: toMessage(ChatMessage(chat))}); got != tt.want {
+				t.Errorf("filter = %v, want %v", got, tt.want)
+			}
+			event := &Context{Message: &Message{Type: TypeEvent}}
+			if tt.filter(event) {
+				t.Error("filter allows an event message")
+			}
+		})
+	}
+}
+
+func TestChatChainFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		admin   bool
+		trace   []string
+		replies []string
+	}{
+		{name: "allowed", text: "/deploy api", admin: true, trace: []string{"top", "deploy"}},
+		{name: "rejected with reply", text: "/deploy api", trace: []string{}, replies: []string{"admins only"}},
+		{name: "skipped", text: "/status", trace: []string{}},
+		{name: "group filtered", text: "/k8s pods", trace: []string{}},
+		{name: "unfiltered", text: "/ping", trace: []string{"top", "ping"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &tracer{}
+			chain := ModuleChatChain().Use(tr.middleware("top"))
+			chain.Handle("deploy", tr.handler("deploy"), WithFilters(AdminOnly()), WithRejectReply("admins only"))
+			chain.Handle("status", tr.handler("status"), WithFilters(InConversations("other")))
+			chain.Group("k8s", WithFilters(FromSenders("ops"))).Handle("pods", tr.handler("pods"))
+			chain.Handle("ping", tr.handler("ping"))
+
+			client := newTestClient(t)
+			client.Register(TypeChat, chain)
+			chat := groupChat(tt.text)
+			chat.IsAdmin = tt.admin
+			if err := client.onMessage(toMessage(ChatMessage(chat))); err != nil {
+				t.Fatal(err)
+			}
+			if trace := append([]string{}, *tr...); !reflect.DeepEqual(trace, tt.trace) {
+				t.Errorf("trace = %q, want %q", trace, tt.trace)
+			}
+			replies := []string{}
+			for _, msg := range queued(client, "cid") {
+				replies = append(replies, msg.(*DingTalkMessage).MsgParam["content"])
+			}
+			want := append([]string{}, tt.replies...)
+			if !reflect.DeepEqual(replies, want) {
+				t.Errorf("replies = %q, want %q", replies, want)
+			}
+		})
+	}
+}
+
+func TestChainFilters(t *testing.T) {
+	tests := []struct {
+		name        string
+		rejectReply string
+		admin       bool
+		handled     bool
+		replies     int
+	}{
+		{name: "allowed", admin: true, handled: true},
+		{name: "skipped"},
+		{name: "rejected", rejectReply: "admins only", replies: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := false
+			client := newTestClient(t)
+			client.Register(TypeChat, ModuleChain().
+				Filter(AdminOnly()).
+				RejectReply(tt.rejectReply).
+				Handle(func(ctx *Context) { handled = true }))
+			chat := groupChat("hello")
+			chat.IsAdmin = tt.admin
+			if err := client.onMessage(toMessage(ChatMessage(chat))); err != nil {
+				t.Fatal(err)
+			}
+			if handled != tt.handled {
+				t.Errorf("handled = %v, want %v", handled, tt.handled)
+			}
+			if n := len(queued(client, "cid")); n != tt.replies {
+				t.Errorf("replied %d times, want %d", n, tt.replies)
+			}
+		})
+	}
+}
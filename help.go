@@ -0,0 +1,178 @@
+package dingtalkbot
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// CommandOption attaches metadata to a command of ChatChain
+type CommandOption func(*chatCommand)
+
+func (cmd *chatCommand) apply(options []CommandOption) {
+	for _, option := range options {
+		option(cmd)
+	}
+}
+
+// WithDescription describes what the command does in one line
+func WithDescription(description string) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.description = description
+	}
+}
+
+// WithUsage describes the arguments of the command, e.g. "<service> [version]"
+func WithUsage(usage string) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.usage = usage
+	}
+}
+
+// WithExamples shows how the command is typed
+func WithExamples(examples ...string) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.examples = append(cmd.examples, examples...)
+	}
+}
+
+// WithAliases lets the command be typed by other names too, with the same
+// prefix, e.g. WithAliases("部署") for "deploy"
+func WithAliases(aliases ...string) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.aliases = append(cmd.aliases, aliases...)
+	}
+}
+
+// WithHidden keeps the command out of the help
+func WithHidden() CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.hidden = true
+	}
+}
+
+// WithVisibility shows the command in the help only if visible returns true
+// for the caller
+func WithVisibility(visible func(ctx *Context) bool) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.visible = visible
+	}
+}
+
+// visibleTo hides the command from callers which its filters don't allow
+func (cmd *chatCommand) visibleTo(ctx *Context) bool {
+	if cmd.hidden || !passFilters(ctx, cmd.filters) {
+		return false
+	}
+	return cmd.visible == nil || cmd.visible(ctx)
+}
+
+// Help registers the built-in help command, "/help" lists the commands which
+// the caller can see, and "/help <command> [subcommand...]" shows the details
+// of a command
+func (c *ChatChain) Help(options ...CommandOption) *ChatChain {
+	options = append([]CommandOption{
+		WithDescription("show the available commands"),
+		WithUsage("[command] [subcommand...]"),
+	}, options...)
+	return c.Handle("help", c.helpHandler, options...)
+}
+
+func (c *ChatChain) helpHandler(ctx *Context) {
+	_ = ctx.ReplyMarkdown("help", c.renderHelp(ctx, ctx.Args()))
+}
+
+func (c *ChatChain) renderHelp(ctx *Context, path []string) string {
+	chain, parents := c, []string{}
+	var cmd *chatCommand
+	for _, name := range path {
+		if chain == nil {
+			break
+		}
+		found, ok := chain.commands.Get(chain.trimPrefix(name))
+		if !ok || !found.visibleTo(ctx) {
+			return fmt.Sprintf("unknown command `%s`", strings.Join(append(parents, name), " "))
+		}
+		parents = append(parents, chain.formatPrefix(found.name))
+		cmd, chain = found, found.group
+	}
+
+	sb := &strings.Builder{}
+	if cmd != nil {
+		full := strings.Join(parents, " ")
+		sb.WriteString(fmt.Sprintf("### %s\n\n", full))
+		if cmd.description != "" {
+			sb.WriteString(cmd.description + "\n\n")
+		}
+		if len(cmd.aliases) > 0 {
+			sb.WriteString(fmt.Sprintf("**aliases:** %s\n\n", strings.Join(cmd.aliases, ", ")))
+		}
+		if cmd.usage != "" {
+			sb.WriteString(fmt.Sprintf("**usage:** `%s %s`\n\n", full, cmd.usage))
+		}
+		for _, example := range cmd.examples {
+			sb.WriteString(fmt.Sprintf("- `%s`\n", example))
+		}
+		if len(cmd.examples) > 0 {
+			sb.WriteString("\n")
+		}
+	}
+	if chain != nil {
+		prefix := strings.Join(parents, " ")
+		if prefix != "" {
+			prefix += " "
+		}
+		listed := chain.renderCommands(ctx, prefix)
+		if listed == "" && cmd == nil {
+			return "no commands available"
+		}
+		sb.WriteString(listed)
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func (c *ChatChain) renderCommands(ctx *Context, prefix string) string {
+	// aliases share the command, so each command is listed once
+	cmds := []*chatCommand{}
+	seen := make(map[*chatCommand]bool)
+	c.commands.Each(func(_ string, cmd *chatCommand) bool {
+		if !seen[cmd] && cmd.visibleTo(ctx) {
+			cmds = append(cmds, cmd)
+		}
+		seen[cmd] = true
+		return true
+	})
+	slices.SortFunc(cmds, func(a, b *chatCommand) int {
+		return strings.Compare(a.name, b.name)
+	})
+	patterns := []*chatCommand{}
+	for _, cmd := range c.patterns {
+		if cmd.visibleTo(ctx) {
+			patterns = append(patterns, cmd)
+		}
+	}
+
+	sb := &strings.Builder{}
+	for _, cmd := range cmds {
+		line := prefix + c.formatPrefix(cmd.name)
+		if cmd.group != nil {
+			line += " ..."
+		}
+		sb.WriteString(fmt.Sprintf("- `%s`", line))
+		if len(cmd.aliases) > 0 {
+			sb.WriteString(fmt.Sprintf(" (%s)", strings.Join(cmd.aliases, ", ")))
+		}
+		if cmd.description != "" {
+			sb.WriteString(" " + cmd.description)
+		}
+		sb.WriteString("\n")
+	}
+	for _, cmd := range patterns {
+		sb.WriteString(fmt.Sprintf("- `%s%s`", prefix, cmd.name))
+		if cmd.description != "" {
+			sb.WriteString(" " + cmd.description)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,110 @@
+package dingtalkbot
+
+import (
+	"testing"
+)
+
+func newHelpChain() *ChatChain {
+	nop := func(ctx *Context) {}
+	chain := ModuleChatChain().Help()
+	chain.Handle("deploy", nop,
+		WithDescription("deploy a service"),
+		WithUsage("<service>"),
+		WithExamples("/deploy api"),
+		WithAliases("部署"))
+	chain.Handle("secret", nop, WithHidden())
+	chain.Handle("admin", nop, WithDescription("admin tools"), WithFilters(AdminOnly()))
+	chain.Handle("beta", nop, WithVisibility(func(ctx *Context) bool {
+		return ctx.Message.Chat().SenderStaffId == "tester"
+	}))
+	chain.Group("k8s", WithDescription("kubernetes")).
+		Handle("pods", nop, WithDescription("list pods"))
+	chain.HandlePattern("rollback {service}", nop, WithDescription("roll back a service"))
+	return chain
+}
+
+func TestRenderHelp(t *testing.T) {
+	tests := []struct {
+		name string
+		chat func(chat ChatMessage)
+		path []string
+		want string
+	}{
+		{
+			name: "list",
+			path: []string{},
+			want: "- `/deploy` (部署) deploy a service\n" +
+				"- `/help` show the available commands\n" +
+				"- `/k8s ...` kubernetes\n" +
+				"- `rollback {service}` roll back a service",
+		},
+		{
+			name: "list for admins and testers",
+			chat: func(chat ChatMessage) { chat.IsAdmin, chat.SenderStaffId = true, "tester" },
+			path: []string{},
+			want: "- `/admin` admin tools\n" +
+				"- `/beta`\n" +
+				"- `/deploy` (部署) deploy a service\n" +
+				"- `/help` show the available commands\n" +
+				"- `/k8s ...` kubernetes\n" +
+				"- `rollback {service}` roll back a service",
+		},
+		{
+			name: "command",
+			path: []string{"deploy"},
+			want: "### /deploy\n\n" +
+				"deploy a service\n\n" +
+				"**aliases:** 部署\n\n" +
+				"**usage:** `/deploy <service>`\n\n" +
+				"- `/deploy api`",
+		},
+		{
+			name: "command with prefix",
+			path: []string{"/deploy"},
+			want: "### /deploy\n\n" +
+				"deploy a service\n\n" +
+				"**aliases:** 部署\n\n" +
+				"**usage:** `/deploy <service>`\n\n" +
+				"- `/deploy api`",
+		},
+		{
+			name: "group",
+			path: []string{"k8s"},
+			want: "### /k8s\n\n" +
+				"kubernetes\n\n" +
+				"- `/k8s pods` list pods",
+		},
+		{
+			name: "subcommand",
+			path: []string{"k8s", "pods"},
+			want: "### /k8s pods\n\nlist pods",
+		},
+		{
+			name: "unknown subcommand",
+			path: []string{"k8s", "nodes"},
+			want: "unknown command `/k8s nodes`",
+		},
+		{
+			name: "hidden command",
+			path: []string{"secret"},
+			want: "unknown command `secret`",
+		},
+		{
+			name: "filtered command",
+			path: []string{"admin"},
+			want: "unknown command `admin`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := groupChat("/help")
+			if tt.chat != nil {
+				tt.chat(chat)
+			}
+			ctx := &Context{Message: toMessage(ChatMessage(chat))}
+			if got := newHelpChain().renderHelp(ctx, tt.path); got != tt.want {
+				t.Errorf("renderHelp(%q) =\n%s\nwant\n%s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
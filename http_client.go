@@ -3,52 +3,126 @@ package dingtalkbot
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/go-resty/resty/v2"
-	"github.com/mitchellh/mapstructure"
 	"net/http"
 	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/go-resty/resty/v2"
 )
 
 const baseUrl = "https://api.dingtalk.com"
 const baseTimeout = 5 * time.Second
 
 type reqHeader struct {
-	AccessToken string `json:"x-acs-dingtalk-access-token" mapstructure:"x-acs-dingtalk-access-token"`
-}
-
-var client = resty.New().
-	SetBaseURL(baseUrl).
-	SetContentLength(true).
-	SetTimeout(baseTimeout).
-	OnBeforeRequest(func(client *resty.Client, request *resty.Request) error {
-		header, _ := json.Marshal(request.Header)
-		body, _ := json.Marshal(request.Body)
-		logger.Debug(
-			"REQUESTING",
-			"url", request.URL,
-			"method", request.Method,
-			"headers", string(header),
-			"body", string(body),
-		)
-		return nil
-	})
-
-func request(headers *reqHeader) *resty.Request {
-	req := client.R()
-	if headers != nil {
-		headersMap := make(map[string]string)
-		err := mapstructure.Decode(headers, &headersMap)
-		if err != nil {
-			logger.Warn("convert headers failed", "err", err)
-			return req
+	AccessToken string
+}
+
+// httpClient is the HTTP layer of a Client, its settings survive replacing
+// the underlying http.Client
+type httpClient struct {
+	client *resty.Client
+
+	baseUrl string
+	timeout time.Duration
+	// timeoutSet is true once the timeout is set by Client.Timeout, it wins
+	// over the timeout of a replaced http.Client then
+	timeoutSet bool
+
+	userAgent string
+	proxy     string
+	transport http.RoundTripper
+}
+
+func newHttpClient() *httpClient {
+	h := &httpClient{
+		baseUrl: baseUrl,
+		timeout: baseTimeout,
+	}
+	h.setClient(&http.Client{})
+	return h
+}
+
+// setClient uses a copy of hc, the settings are never written back to it. The
+// timeout of hc is kept unless it's zero or a timeout was set explicitly.
+func (h *httpClient) setClient(hc *http.Client) {
+	if !h.timeoutSet {
+		h.timeout = baseTimeout
+		if hc.Timeout > 0 {
+			h.timeout = hc.Timeout
 		}
-		req.SetHeaders(headersMap)
+	}
+	copied := *hc
+	h.client = resty.NewWithClient(&copied).
+		SetContentLength(true).
+		OnBeforeRequest(func(client *resty.Client, request *resty.Request) error {
+			// encoding the body again only pays off when it's printed
+			if logger.GetLevel() > log.DebugLevel {
+				return nil
+			}
+			header, _ := json.Marshal(request.Header)
+			body, _ := json.Marshal(request.Body)
+			logger.Debug(
+				"REQUESTING",
+				"url", request.URL,
+				"method", request.Method,
+				"headers", string(header),
+				"body", string(body),
+			)
+			return nil
+		})
+	h.apply()
+}
+
+func (h *httpClient) apply() {
+	h.client.SetBaseURL(h.baseUrl)
+	h.client.SetTimeout(h.timeout)
+	if h.userAgent != "" {
+		h.client.SetHeader("User-Agent", h.userAgent)
+	}
+	if h.transport != nil {
+		h.client.SetTransport(h.transport)
+	}
+	if h.proxy != "" {
+		h.applyProxy()
+	}
+}
+
+// applyProxy sets the proxy on a copy of the transport, so a transport shared
+// with others isn't changed. Only an *http.Transport can carry a proxy.
+func (h *httpClient) applyProxy() {
+	transport, err := h.client.Transport()
+	if err != nil {
+		logger.Error("proxy is ignored, the transport must be an *http.Transport to use it",
+			"proxy", h.proxy, "transport", fmt.Sprintf("%T", h.client.GetClient().Transport))
+		return
+	}
+	h.client.SetTransport(transport.Clone())
+	h.client.SetProxy(h.proxy)
+}
+
+func (h *httpClient) request(headers *reqHeader) *resty.Request {
+	req := h.client.R()
+	if headers != nil && headers.AccessToken != "" {
+		req.SetHeader("x-acs-dingtalk-access-token", headers.AccessToken)
 	}
 	return req
 }
 
-func post(path string, body map[string]any, headers *reqHeader) ([]byte, error) {
-	resp, err := request(headers).
+// post encodes body as json, and decodes the response into result if it's
+// not nil
+func (h *httpClient) post(path string, body any, headers *reqHeader, result any) error {
+	respBody, err := h.postRequest(h.request(headers), path, body)
+	if err != nil {
+		return err
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+func (h *httpClient) postRequest(req *resty.Request, path string, body any) ([]byte, error) {
+	resp, err := req.
 		SetBody(body).
 		SetHeader("Content-Type", "application/json").
 		Post(path)
@@ -56,7 +130,7 @@ func post(path string, body map[string]any, headers *reqHeader) ([]byte, error)
 		return nil, err
 	}
 	if resp.StatusCode() != http.StatusOK {
-		return nil, fmt.Errorf("response status code: %d, body=%v", resp.StatusCode(), string(resp.Body()))
+		return nil, newAPIError(resp.StatusCode(), resp.Body())
 	}
 	return resp.Body(), nil
 }
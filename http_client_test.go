@@ -0,0 +1,73 @@
+package dingtalkbot
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSetClientKeepsCallerClient(t *testing.T) {
+	hc := &http.Client{Timeout: time.Minute}
+	h := newHttpClient()
+	h.setClient(hc)
+	if hc.Timeout != time.Minute || hc.Transport != nil {
+		t.Errorf("caller's client was changed: timeout=%v transport=%T", hc.Timeout, hc.Transport)
+	}
+	if got := h.client.GetClient().Timeout; got != time.Minute {
+		t.Errorf("timeout = %v, want the caller's %v", got, time.Minute)
+	}
+}
+
+func TestSetClientTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		timeoutSet bool
+		hc         *http.Client
+		want       time.Duration
+	}{
+		{name: "caller's timeout", hc: &http.Client{Timeout: time.Minute}, want: time.Minute},
+		{name: "no timeout falls back to the default", hc: &http.Client{}, want: baseTimeout},
+		{name: "explicit timeout wins", timeout: time.Second, timeoutSet: true, hc: &http.Client{Timeout: time.Minute}, want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHttpClient()
+			if tt.timeoutSet {
+				h.timeout, h.timeoutSet = tt.timeout, true
+			}
+			h.setClient(tt.hc)
+			if got := h.client.GetClient().Timeout; got != tt.want {
+				t.Errorf("timeout = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyProxy(t *testing.T) {
+	shared := &http.Transport{}
+	h := newHttpClient()
+	h.transport = shared
+	h.proxy = "http://127.0.0.1:8080"
+	h.apply()
+	if shared.Proxy != nil {
+		t.Error("proxy was set on the shared transport")
+	}
+	transport, err := h.client.Transport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://api.dingtalk.com", nil)
+	proxy, err := transport.Proxy(req)
+	if err != nil || proxy == nil || proxy.Host != "127.0.0.1:8080" {
+		t.Errorf("proxy = %v, %v, want 127.0.0.1:8080", proxy, err)
+	}
+
+	// a custom RoundTripper can't carry the proxy, it's kept as is
+	custom := &stubTransport{status: http.StatusOK}
+	h.transport = custom
+	h.apply()
+	if h.client.GetClient().Transport != custom {
+		t.Errorf("transport = %T, want the custom one", h.client.GetClient().Transport)
+	}
+}
@@ -0,0 +1,91 @@
+package dingtalkbot
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/open-dingtalk/dingtalk-stream-sdk-go/chatbot"
+)
+
+// AtUser is a user @mentioned in a chat message
+type AtUser = chatbot.BotCallbackDataAtUserModel
+
+// BotName tells the chain how the bot is @mentioned, e.g. "OurBot", so the
+// mention is stripped before matching commands
+func (c *ChatChain) BotName(names ...string) *ChatChain {
+	c.botNames = append(c.botNames, names...)
+	return c
+}
+
+// normalizeText removes the @mentions of the bot and trims any whitespace,
+// full-width spaces included. Without a bot name, a leading @mention is
+// stripped only if the bot was @mentioned and a command prefix follows it.
+func (c *ChatChain) normalizeText(chat ChatMessage) string {
+	text := strings.TrimFunc(chat.Text.Content, unicode.IsSpace)
+	for _, name := range c.botNames {
+		text = removeMention(text, "@"+name)
+	}
+	if len(c.botNames) == 0 && chat.IsInAtList && strings.HasPrefix(text, "@") {
+		end := strings.IndexFunc(text, unicode.IsSpace)
+		if end > 0 {
+			rest := strings.TrimLeftFunc(text[end:], unicode.IsSpace)
+			for _, prefix := range c.prefixes {
+				if prefix != "" && strings.HasPrefix(rest, prefix) {
+					text = rest
+					break
+				}
+			}
+		}
+	}
+	return strings.TrimFunc(text, unicode.IsSpace)
+}
+
+// removeMention removes every mention which is surrounded by whitespace or
+// the ends of text, e.g. not the one in an email address
+func removeMention(text, mention string) string {
+	sb := &strings.Builder{}
+	// whether text follows whitespace or the start of the whole text
+	boundary := true
+	for {
+		i := strings.Index(text, mention)
+		if i < 0 {
+			sb.WriteString(text)
+			return sb.String()
+		}
+		end := i + len(mention)
+		prev, _ := utf8.DecodeLastRuneInString(text[:i])
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if (i > 0 && !unicode.IsSpace(prev)) || (i == 0 && !boundary) ||
+			(end < len(text) && !unicode.IsSpace(next)) {
+			sb.WriteString(text[:end])
+			text = text[end:]
+			boundary = false
+			continue
+		}
+		sb.WriteString(text[:i])
+		text = strings.TrimLeftFunc(text[end:], unicode.IsSpace)
+		boundary = true
+	}
+}
+
+// Mentions returns the users @mentioned in the chat message, except the bot
+func (c *Context) Mentions() []AtUser {
+	if c.Message.Type != TypeChat {
+		return nil
+	}
+	chat := c.Message.Chat()
+	mentions := []AtUser{}
+	for _, user := range chat.AtUsers {
+		if user.DingtalkId != chat.ChatbotUserId {
+			mentions = append(mentions, user)
+		}
+	}
+	return mentions
+}
+
+// Text returns the chat message text which commands were matched against,
+// without the @mentions of the bot
+func (c *Context) Text() string {
+	return c.text
+}
@@ -0,0 +1,64 @@
+package dingtalkbot
+
+import (
+	"testing"
+
+	"github.com/open-dingtalk/dingtalk-stream-sdk-go/chatbot"
+)
+
+func TestRemoveMention(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"@bot /deploy", "/deploy"},
+		{"@bot　/deploy", "/deploy"},
+		{"/deploy @bot", "/deploy "},
+		{"@bot", ""},
+		{"@bot @bot /deploy", "/deploy"},
+		{"@botanist /deploy", "@botanist /deploy"},
+		{"@bot,/deploy", "@bot,/deploy"},
+		{"@bot2 @bot /deploy", "@bot2 /deploy"},
+		{"ask@bot now", "ask@bot now"},
+		{"ask @bot now", "ask now"},
+		{"@bot@bot x", "@bot@bot x"},
+		{"/deploy", "/deploy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := removeMention(tt.text, "@bot"); got != tt.want {
+				t.Errorf("removeMention(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name       string
+		botNames   []string
+		text       string
+		isInAtList bool
+		want       string
+	}{
+		{name: "bot name", botNames: []string{"bot"}, text: " @bot /deploy api ", want: "/deploy api"},
+		{name: "full-width spaces", botNames: []string{"bot"}, text: "　@bot　/deploy　", want: "/deploy"},
+		{name: "trailing mention", botNames: []string{"bot"}, text: "/deploy api @bot", want: "/deploy api"},
+		{name: "other bot name", botNames: []string{"bot", "机器人"}, text: "@机器人 /deploy", want: "/deploy"},
+		{name: "longer name kept", botNames: []string{"bot"}, text: "@botanist /deploy", want: "@botanist /deploy"},
+		{name: "unnamed mention with prefix", text: "@someone /deploy", isInAtList: true, want: "/deploy"},
+		{name: "unnamed mention without prefix", text: "@someone hello", isInAtList: true, want: "@someone hello"},
+		{name: "not at the bot", text: "@someone /deploy", want: "@someone /deploy"},
+		{name: "mention only", text: "@someone", isInAtList: true, want: "@someone"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ModuleChatChain().BotName(tt.botNames...)
+			chat := &chatbot.BotCallbackDataModel{IsInAtList: tt.isInAtList}
+			chat.Text.Content = tt.text
+			if got := c.normalizeText(chat); got != tt.want {
+				t.Errorf("normalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
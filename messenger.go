@@ -2,6 +2,8 @@ package dingtalkbot
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,10 +13,16 @@ import (
 )
 
 const (
-	iMQScanInterval = time.Second
-	iCachePrefix    = "message_%s_"
+	iMQScanInterval       = time.Second
+	iCachePrefix          = "message_%s_"
+	iIdempotentKey        = "idempotent_%s"
+	iDefaultDedupTTL      = 10 * time.Minute
+	iDefaultContactsTTL   = time.Hour
+	iDefaultDownloadLimit = 20 << 20
 )
 
+var ErrDuplicateMessage = errors.New("message with the same idempotency key was already sent")
+
 type Messenger struct {
 	cache *badger.DB
 
@@ -22,8 +30,14 @@ type Messenger struct {
 	mq      chan Sendable
 	storage map[string]string
 
-	accessToken string
-	tokenExpiry time.Time
+	outbound    []OutboundFunc
+	dedupTTL    time.Duration
+	contactsTTL time.Duration
+
+	downloadLimit int64
+
+	api    *openApi
+	legacy *legacyApi
 }
 
 func (m *Messenger) start(ctx context.Context) {
@@ -58,22 +72,18 @@ func (m *Messenger) startMessageQueueHandler(ctx context.Context) {
 
 func (m *Messenger) startAccessTokenRefresher(ctx context.Context) {
 	logger.Debug("starting AccessTokenRefresher")
+	interval := time.Duration(0)
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			if time.Now().Add(5 * time.Minute).After(m.tokenExpiry) {
-				params := m.requireParams("clientId", "clientSecret")
-				token, expireSec, err := getAccessToken(params["clientId"], params["clientSecret"])
-				if err != nil {
-					logger.Error("refresh access token failed", "err", err)
-					continue
-				}
-				m.accessToken = token
-				m.tokenExpiry = time.Now().Add(time.Duration(expireSec) * time.Second)
+		case <-time.After(interval):
+			// keep the token warm, sending still refreshes it on demand
+			_, err := m.api.tokens.Token()
+			if err != nil {
+				logger.Error("refresh access token failed", "err", err)
 			}
-			time.Sleep(time.Minute)
+			interval = time.Minute
 		}
 	}
 }
@@ -89,14 +99,19 @@ func (m *Messenger) enqueueMessage(msg Sendable) {
 }
 
 func (m *Messenger) handleMessage(msg Sendable) {
-	if time.Now().After(m.tokenExpiry) {
-		logger.Error("failed to send message because access token was expired, re-add message to queue")
+	_, err := m.api.sendMessage(msg)
+	if errors.Is(err, ErrTokenUnavailable) {
+		logger.Error("failed to send message because access token is unavailable, re-add message to queue", "err", err)
 		m.enqueueMessage(msg)
 		return
 	}
-	_, err := sendMessage(m.accessToken, msg)
 	if err != nil {
 		logger.Error("failed to send message, throw away it", "err", err)
+		// let a retry of the dropped message through
+		err = m.cacheRelease(msg)
+		if err != nil {
+			logger.Error("failed to release idempotency key", "err", err)
+		}
 		return
 	}
 	err = m.cachePut(msg)
@@ -156,6 +171,73 @@ func (m *Messenger) cachePut(msg Sendable) error {
 	})
 }
 
+// cacheLoad decodes the json value of key into v, ok is false if key isn't
+// cached or has expired
+func (m *Messenger) cacheLoad(key string, v any) (ok bool, err error) {
+	err = m.cache.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			ok = true
+			return json.Unmarshal(val, v)
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// cacheStore caches v as json under key for ttl
+func (m *Messenger) cacheStore(key string, v any, ttl time.Duration) error {
+	val, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return m.cache.Update(func(txn *badger.Txn) error {
+		entry := badger.NewEntry([]byte(key), val).WithTTL(ttl)
+		return txn.SetEntry(entry)
+	})
+}
+
+// cacheClaim stores the idempotency key of msg, it returns ErrDuplicateMessage
+// if the key is already there
+func (m *Messenger) cacheClaim(msg Sendable) error {
+	idempotent, ok := msg.(Idempotent)
+	if !ok || idempotent.IdempotencyKey() == "" {
+		return nil
+	}
+	return m.cache.Update(func(txn *badger.Txn) error {
+		cacheKey := []byte(fmt.Sprintf(iIdempotentKey, idempotent.IdempotencyKey()))
+		_, err := txn.Get(cacheKey)
+		switch {
+		case err == nil:
+			return ErrDuplicateMessage
+		case !errors.Is(err, badger.ErrKeyNotFound):
+			return err
+		}
+		entry := badger.NewEntry(cacheKey, []byte(msg.OpenConversationId())).WithTTL(m.dedupTTL)
+		return txn.SetEntry(entry)
+	})
+}
+
+// cacheRelease removes the idempotency key of msg, so a message with the same
+// key can be sent again
+func (m *Messenger) cacheRelease(msg Sendable) error {
+	idempotent, ok := msg.(Idempotent)
+	if !ok || idempotent.IdempotencyKey() == "" {
+		return nil
+	}
+	return m.cache.Update(func(txn *badger.Txn) error {
+		return txn.Delete([]byte(fmt.Sprintf(iIdempotentKey, idempotent.IdempotencyKey())))
+	})
+}
+
 func (m *Messenger) requireParams(keys ...string) (params map[string]string) {
 	params = make(map[string]string)
 	for _, key := range keys {
@@ -0,0 +1,104 @@
+package dingtalkbot
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	iContactsUserKey      = "contacts_user_%s"
+	iContactsUnionIdKey   = "contacts_unionid_%s"
+	iContactsMobileKey    = "contacts_mobile_%s"
+	iContactsDeptKey      = "contacts_dept_%d"
+	iContactsSubDeptsKey  = "contacts_subdepts_%d"
+	iContactsDeptUsersKey = "contacts_deptusers_%d"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// cached loads key from the cache into v, or fills v with fetch and caches it
+// for the contacts TTL, the cache is bypassed if the TTL isn't positive
+func cached[T any](m *Messenger, key string, fetch func() (T, error)) (T, error) {
+	var v T
+	if m.contactsTTL <= 0 {
+		return fetch()
+	}
+	ok, err := m.cacheLoad(key, &v)
+	if err != nil {
+		logger.Warn("failed to load contacts from cache", "key", key, "err", err)
+	}
+	if ok {
+		return v, nil
+	}
+	v, err = fetch()
+	if err != nil {
+		return v, err
+	}
+	err = m.cacheStore(key, v, m.contactsTTL)
+	if err != nil {
+		logger.Warn("failed to cache contacts", "key", key, "err", err)
+	}
+	return v, nil
+}
+
+// GetUser returns the user of userId (the staff id), results are cached
+func (m *Messenger) GetUser(userId string) (*User, error) {
+	return cached(m, fmt.Sprintf(iContactsUserKey, userId), func() (*User, error) {
+		user, err := m.legacy.getUser(userId)
+		if err == nil && user == nil {
+			err = ErrUserNotFound
+		}
+		return user, err
+	})
+}
+
+// GetUserByUnionId returns the user of unionId, results are cached
+func (m *Messenger) GetUserByUnionId(unionId string) (*User, error) {
+	userId, err := cached(m, fmt.Sprintf(iContactsUnionIdKey, unionId), func() (string, error) {
+		return m.legacy.getUserIdByUnionId(unionId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if userId == "" {
+		return nil, ErrUserNotFound
+	}
+	return m.GetUser(userId)
+}
+
+// GetUserByMobile returns the user of mobile, results are cached
+func (m *Messenger) GetUserByMobile(mobile string) (*User, error) {
+	userId, err := cached(m, fmt.Sprintf(iContactsMobileKey, mobile), func() (string, error) {
+		return m.legacy.getUserIdByMobile(mobile)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if userId == "" {
+		return nil, ErrUserNotFound
+	}
+	return m.GetUser(userId)
+}
+
+// GetDepartment returns the department of deptId, results are cached
+func (m *Messenger) GetDepartment(deptId int64) (*Department, error) {
+	return cached(m, fmt.Sprintf(iContactsDeptKey, deptId), func() (*Department, error) {
+		return m.legacy.getDepartment(deptId)
+	})
+}
+
+// ListSubDepartments returns the direct sub departments of deptId, results
+// are cached
+func (m *Messenger) ListSubDepartments(deptId int64) ([]*Department, error) {
+	return cached(m, fmt.Sprintf(iContactsSubDeptsKey, deptId), func() ([]*Department, error) {
+		return m.legacy.listSubDepartments(deptId)
+	})
+}
+
+// ListDepartmentMembers returns the direct members of deptId, results are
+// cached
+func (m *Messenger) ListDepartmentMembers(deptId int64) ([]*User, error) {
+	return cached(m, fmt.Sprintf(iContactsDeptUsersKey, deptId), func() ([]*User, error) {
+		return m.legacy.listUsers(deptId)
+	})
+}
@@ -0,0 +1,7 @@
+package dingtalkbot
+
+// DownloadFile exchanges the downloadCode of a picture, file, audio or video
+// message for its content, the body is limited to the download limit
+func (m *Messenger) DownloadFile(downloadCode string) (*Download, error) {
+	return m.api.downloadMessageFile(downloadCode, m.requireParams("robotCode")["robotCode"], m.downloadLimit)
+}
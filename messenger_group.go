@@ -0,0 +1,27 @@
+package dingtalkbot
+
+// CreateSceneGroup creates a scene group from a template, the returned
+// OpenConversationId plugs directly into SendTextMessage
+func (m *Messenger) CreateSceneGroup(opts *SceneGroupOptions) (*SceneGroup, error) {
+	return m.legacy.createSceneGroup(opts)
+}
+
+// UpdateSceneGroup changes the title or owner of a scene group
+func (m *Messenger) UpdateSceneGroup(openConversationId string, update *SceneGroupUpdate) error {
+	return m.legacy.updateSceneGroup(openConversationId, update)
+}
+
+// GetSceneGroup fetches the info of a scene group
+func (m *Messenger) GetSceneGroup(openConversationId string) (*SceneGroup, error) {
+	return m.legacy.getSceneGroup(openConversationId)
+}
+
+// AddSceneGroupMembers adds users into a scene group
+func (m *Messenger) AddSceneGroupMembers(openConversationId string, userIds ...string) error {
+	return m.legacy.sceneGroupMembers(legacyApiAddSceneGroupMembers, openConversationId, userIds)
+}
+
+// RemoveSceneGroupMembers removes users from a scene group
+func (m *Messenger) RemoveSceneGroupMembers(openConversationId string, userIds ...string) error {
+	return m.legacy.sceneGroupMembers(legacyApiDeleteSceneGroupMembers, openConversationId, userIds)
+}
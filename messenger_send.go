@@ -1,30 +1,85 @@
 package dingtalkbot
 
-func (m *Messenger) Send(msg Sendable) {
+import (
+	"errors"
+)
+
+// Send passes msg through the outbound middlewares and queues it, the error
+// is non-nil if any middleware rejected the message, or it's a duplicate of
+// a message with the same idempotency key
+func (m *Messenger) Send(msg Sendable) error {
+	msg, err := m.applyOutbound(msg)
+	if err != nil {
+		logger.Warn("message was rejected, throw away it", "err", err)
+		return err
+	}
+	err = m.cacheClaim(msg)
+	if err != nil {
+		if errors.Is(err, ErrDuplicateMessage) {
+			logger.Debug("duplicated message, throw away it", "conversationId", msg.OpenConversationId())
+		} else {
+			logger.Error("failed to check idempotency key", "err", err)
+		}
+		return err
+	}
 	m.enqueueMessage(msg)
+	return nil
 }
 
-func (m *Messenger) SendTextMessage(conversationId, text string) {
-	msg := &DingTalkMessage{
-		MsgKey: "sampleText",
-		MsgParam: map[string]string{
-			"content": text,
-		},
-		extras:         m.requireParams("robotCode"),
+// Message builds a message of msgKey without sending it, see the message
+// templates of DingTalk robots for the keys and their params
+func (m *Messenger) Message(conversationId, msgKey string, msgParam map[string]string) *DingTalkMessage {
+	return &DingTalkMessage{
+		MsgKey:         msgKey,
+		MsgParam:       msgParam,
+		robotCode:      m.requireParams("robotCode")["robotCode"],
 		ConversationId: conversationId,
 	}
-	m.Send(msg)
 }
 
-func (m *Messenger) SendMarkdownMessage(conversationId, title, text string) {
-	msg := &DingTalkMessage{
-		MsgKey: "sampleMarkdown",
-		MsgParam: map[string]string{
+// TextMessage builds a text message without sending it
+func (m *Messenger) TextMessage(conversationId, text string) *DingTalkMessage {
+	return m.Message(conversationId, "sampleText", map[string]string{
+		"content": text,
+	})
+}
+
+// MarkdownMessage builds a markdown message without sending it
+func (m *Messenger) MarkdownMessage(conversationId, title, text string) *DingTalkMessage {
+	return m.Message(conversationId, "sampleMarkdown", map[string]string{
+		"title": title,
+		"text":  text,
+	})
+}
+
+func (m *Messenger) SendTextMessage(conversationId, text string) error {
+	return m.Send(m.TextMessage(conversationId, text))
+}
+
+func (m *Messenger) SendMarkdownMessage(conversationId, title, text string) error {
+	return m.Send(m.MarkdownMessage(conversationId, title, text))
+}
+
+// SendWorkTextNotification sends a text work notification to users directly,
+// it bypasses the message queue and returns the task id of the notification
+func (m *Messenger) SendWorkTextNotification(userIds []string, text string) (int64, error) {
+	return m.legacy.sendWorkNotification(m.requireParams("agentId")["agentId"], userIds, map[string]any{
+		"msgtype": "text",
+		"text": map[string]string{
+			"content": text,
+		},
+	})
+}
+
+// SendWorkMarkdownNotification sends a markdown work notification to users
+// directly, it bypasses the message queue and returns the task id of the
+// notification
+func (m *Messenger) SendWorkMarkdownNotification(userIds []string, title, text string) (int64, error) {
+	return m.legacy.sendWorkNotification(m.requireParams("agentId")["agentId"], userIds, map[string]any{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
 			"title": title,
 			"text":  text,
 		},
-		extras:         m.requireParams("robotCode"),
-		ConversationId: conversationId,
-	}
-	m.Send(msg)
+	})
 }
@@ -0,0 +1,51 @@
+package dingtalkbot
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/dgraph-io/badger/v4"
+	"github.com/zyedidia/generic/queue"
+)
+
+func newTestMessenger(t *testing.T, api *openApi) *Messenger {
+	t.Helper()
+	cache, err := badger.Open(badger.DefaultOptions("").WithInMemory(true).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = cache.Close() })
+	return &Messenger{
+		api:      api,
+		cache:    cache,
+		mqm:      NewRWMap[string, *queue.Queue[Sendable]](),
+		storage:  map[string]string{"robotCode": "robot"},
+		dedupTTL: iDefaultDedupTTL,
+	}
+}
+
+func TestSendDuplicateMessage(t *testing.T) {
+	m := newTestMessenger(t, newStubApi(http.StatusOK, `{}`))
+	send := func() error {
+		return m.Send(m.TextMessage("cid", "alert").WithIdempotencyKey("alert-1"))
+	}
+	if err := send(); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	if err := send(); !errors.Is(err, ErrDuplicateMessage) {
+		t.Fatalf("second send = %v, want ErrDuplicateMessage", err)
+	}
+}
+
+func TestHandleMessageReleasesKeyOnFailure(t *testing.T) {
+	m := newTestMessenger(t, newStubApi(http.StatusBadRequest, `{"code":"invalidParameter"}`))
+	msg := m.TextMessage("cid", "alert").WithIdempotencyKey("alert-1")
+	if err := m.Send(msg); err != nil {
+		t.Fatalf("first send: %v", err)
+	}
+	m.handleMessage(msg)
+	if err := m.Send(msg); err != nil {
+		t.Fatalf("retry after a failed send = %v, want nil", err)
+	}
+}
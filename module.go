@@ -2,6 +2,7 @@ package dingtalkbot
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -9,9 +10,21 @@ type Module interface {
 	parseContext(message *Message) *Context
 }
 
+type DispatchMode int
+
+const (
+	// DispatchFirstMatch handles a message by the first module which has a
+	// handler for it, e.g. a ChatChain with a default handler always has one
+	DispatchFirstMatch DispatchMode = iota
+	// DispatchFanOut handles a message by every module which has a handler
+	// for it
+	DispatchFanOut
+)
+
 // Simple only one handler module
 type Simple struct {
 	handler HandlerFunc
+	timeout handlerTimeout
 }
 
 func ModuleSimple() *Simple {
@@ -29,6 +42,7 @@ func (s *Simple) parseContext(message *Message) *Context {
 		middlewares: nil,
 		handler:     s.handler,
 		args:        []string{},
+		timeout:     s.timeout,
 	}
 }
 
@@ -36,6 +50,9 @@ func (s *Simple) parseContext(message *Message) *Context {
 type Chain struct {
 	middlewares []HandlerFunc
 	handler     HandlerFunc
+	filters     []Filter
+	rejectReply string
+	timeout     handlerTimeout
 }
 
 func ModuleChain() *Chain {
@@ -44,6 +61,7 @@ func ModuleChain() *Chain {
 	}
 }
 
+// Use appends a middleware, it must call Abort to stop the message, see Next
 func (c *Chain) Use(middleware HandlerFunc) *Chain {
 	c.middlewares = append(c.middlewares, middleware)
 	return c
@@ -55,71 +73,270 @@ func (c *Chain) Handle(handler HandlerFunc) *Chain {
 }
 
 func (c *Chain) parseContext(message *Message) *Context {
-	return &Context{
+	ctx := &Context{
 		Message:     message,
 		middlewares: c.middlewares,
 		handler:     c.handler,
 		args:        []string{},
+		timeout:     c.timeout,
+	}
+	if passFilters(ctx, c.filters) {
+		return ctx
 	}
+	if c.rejectReply == "" {
+		return nil
+	}
+	ctx.middlewares, ctx.handler = nil, rejectHandler(c.rejectReply)
+	return ctx
 }
 
-// ChatChain check prefix as command handler module
+// ChatChain check prefix as command handler module, commands can be grouped
+// into nested ChatChains by Group
 type ChatChain struct {
+	prefixes        []string
+	botNames        []string
+	caseInsensitive bool
+	middlewares     []HandlerFunc
+	commands        *commandTrie[*chatCommand]
+	patterns        []*chatCommand
+	defHandler      HandlerFunc
+	timeout         handlerTimeout
+}
+
+// chatCommand is a command registered into ChatChain, it's either handled by
+// handler or routed further into group
+type chatCommand struct {
+	name    string
+	aliases []string
+	handler HandlerFunc
+	group   *ChatChain
+	// pattern routes match the whole text, source is set if pattern was
+	// compiled from a HandlePattern pattern
+	pattern *regexp.Regexp
+	source  string
+
+	filters     []Filter
+	rejectReply string
+	timeout     handlerTimeout
+
+	description string
+	usage       string
+	examples    []string
+	hidden      bool
+	visible     func(*Context) bool
+}
+
+// chatRoute is the result of routing a chat message through ChatChains
+type chatRoute struct {
+	command     string
+	handler     HandlerFunc
 	middlewares []HandlerFunc
-	handlerMap  *RWMap[string, HandlerFunc]
-	defHandler  HandlerFunc
+	rawArgs     string
+	params      map[string]string
+	timeout     handlerTimeout
 }
 
 func ModuleChatChain() *ChatChain {
+	return newChatChain("/")
+}
+
+func newChatChain(prefixes ...string) *ChatChain {
 	return &ChatChain{
+		prefixes:    prefixes,
 		middlewares: []HandlerFunc{},
-		handlerMap:  NewRWMap[string, HandlerFunc](),
+		commands:    newCommandTrie[*chatCommand](false),
 	}
 }
 
+// formatPrefix returns command as it's typed with the primary prefix
 func (c *ChatChain) formatPrefix(command string) string {
-	return fmt.Sprintf("/%s", command)
+	return fmt.Sprintf("%s%s", c.prefixes[0], command)
 }
 
+// trimPrefix returns command without any of the prefixes
+func (c *ChatChain) trimPrefix(command string) string {
+	for _, prefix := range c.prefixes {
+		if prefix != "" && strings.HasPrefix(command, prefix) {
+			return command[len(prefix):]
+		}
+	}
+	return command
+}
+
+// Prefix replaces the command prefix "/", the first one is primary and shown
+// in help, an empty prefix matches commands typed without any prefix
+func (c *ChatChain) Prefix(prefix string, more ...string) *ChatChain {
+	c.prefixes = append([]string{prefix}, more...)
+	return c
+}
+
+// CaseInsensitive matches commands, aliases and subcommands of the chain and
+// its groups regardless of case
+func (c *ChatChain) CaseInsensitive() *ChatChain {
+	c.caseInsensitive = true
+	commands := newCommandTrie[*chatCommand](true)
+	c.commands.Each(func(name string, cmd *chatCommand) bool {
+		commands.Put(name, cmd)
+		if cmd.group != nil {
+			cmd.group.CaseInsensitive()
+		}
+		return true
+	})
+	c.commands = commands
+	for _, cmd := range c.patterns {
+		if cmd.source != "" {
+			cmd.pattern, _ = compilePattern(cmd.source, true)
+		}
+	}
+	return c
+}
+
+// Use appends a middleware, it must call Abort to stop the message, see Next
 func (c *ChatChain) Use(middleware HandlerFunc) *ChatChain {
 	c.middlewares = append(c.middlewares, middleware)
 	return c
 }
 
-func (c *ChatChain) Handle(command string, handler HandlerFunc) *ChatChain {
-	c.handlerMap.Put(c.formatPrefix(command), handler)
+func (c *ChatChain) put(cmd *chatCommand, options []CommandOption) {
+	cmd.apply(options)
+	c.commands.Put(cmd.name, cmd)
+	for _, alias := range cmd.aliases {
+		c.commands.Put(alias, cmd)
+	}
+}
+
+// Handle registers handler for command, if command is a group, handler
+// becomes the default of the group
+func (c *ChatChain) Handle(command string, handler HandlerFunc, options ...CommandOption) *ChatChain {
+	if cmd, ok := c.commands.Get(command); ok && cmd.group != nil {
+		cmd.group.Default(handler)
+		c.put(cmd, options)
+		return c
+	}
+	c.put(&chatCommand{
+		name:    command,
+		handler: handler,
+	}, options)
 	return c
 }
 
+// Group returns the sub ChatChain of command, e.g. Group("k8s").Handle("pods",
+// h) handles "/k8s pods". Subcommands have no prefix, and their middlewares
+// run after the ones of the outer chains. A handler already registered for
+// command becomes the default of the group. Without a default, a message
+// which matches no subcommand is routed as if the group didn't match.
+func (c *ChatChain) Group(command string, options ...CommandOption) *ChatChain {
+	cmd, ok := c.commands.Get(command)
+	if ok && cmd.group != nil {
+		c.put(cmd, options)
+		return cmd.group
+	}
+	group := newChatChain("")
+	if c.caseInsensitive {
+		group.CaseInsensitive()
+	}
+	if !ok {
+		cmd = &chatCommand{name: command}
+	}
+	group.defHandler = cmd.handler
+	cmd.handler, cmd.group = nil, group
+	c.put(cmd, options)
+	return group
+}
+
 func (c *ChatChain) Default(handler HandlerFunc) *ChatChain {
 	c.defHandler = handler
 	return c
 }
 
+// route matches text against the commands of c, and descends into groups
+// until a handler is found, the default of the innermost group wins if no
+// subcommand matches. A group without default which matches nothing falls
+// back to the outer chain as if the group never matched, so neither its
+// middlewares nor its command apply. It returns false if nothing matched.
+func (c *ChatChain) route(ctx *Context, text string, r *chatRoute) bool {
+	r.middlewares = append(r.middlewares, c.middlewares...)
+	r.timeout = c.timeout.override(r.timeout)
+	cmd, rest, ok := c.match(text)
+	if !ok {
+		if cmd, params, ok := c.matchPattern(text); ok {
+			r.command = strings.TrimSpace(r.command + " " + cmd.name)
+			r.handler, r.rawArgs, r.params = cmd.handler, "", params
+			r.timeout = cmd.timeout.override(r.timeout)
+			r.filter(ctx, cmd)
+			return true
+		}
+		return false
+	}
+	outer := *r
+	r.command = strings.TrimSpace(r.command + " " + c.formatPrefix(cmd.name))
+	r.rawArgs = strings.TrimSpace(rest)
+	r.timeout = cmd.timeout.override(r.timeout)
+	if cmd.group == nil {
+		r.handler = cmd.handler
+		r.filter(ctx, cmd)
+		return true
+	}
+	if cmd.group.defHandler != nil {
+		r.handler = cmd.group.defHandler
+	}
+	if !r.filter(ctx, cmd) {
+		return true
+	}
+	if !cmd.group.route(ctx, r.rawArgs, r) && cmd.group.defHandler == nil {
+		*r = outer
+		return false
+	}
+	return true
+}
+
+// filter replaces the handler if ctx doesn't pass the filters of cmd, the
+// message is skipped, or rejected if cmd has a reply for it
+func (r *chatRoute) filter(ctx *Context, cmd *chatCommand) bool {
+	if passFilters(ctx, cmd.filters) {
+		return true
+	}
+	r.handler, r.middlewares = nil, nil
+	if cmd.rejectReply != "" {
+		r.handler = rejectHandler(cmd.rejectReply)
+	}
+	return false
+}
+
+// match tries the prefixes in order, and the longest command wins for each of
+// them, e.g. /deploy-prod over /deploy
+func (c *ChatChain) match(text string) (*chatCommand, string, bool) {
+	for _, prefix := range c.prefixes {
+		if !strings.HasPrefix(text, prefix) {
+			continue
+		}
+		if cmd, rest, ok := c.commands.Match(text[len(prefix):]); ok {
+			return cmd, rest, true
+		}
+	}
+	return nil, "", false
+}
+
 func (c *ChatChain) parseContext(message *Message) *Context {
 	if message.Type != TypeChat {
 		return nil
 	}
-	content := strings.TrimLeft(message.Chat().Text.Content, " ")
-	handler := c.defHandler
-	args := []string{}
-	c.handlerMap.Each(func(command string, h HandlerFunc) bool {
-		// if command start with arguments, must is a space of suffix
-		switch {
-		case strings.HasPrefix(content, command+" "):
-			args = strings.Split(content[len(command)+1:], " ")
-			handler = h
-			return false
-		case content == command:
-			handler = h
-			return false
-		}
-		return true
-	})
-	return &Context{
-		Message:     message,
-		middlewares: c.middlewares,
-		handler:     handler,
-		args:        args,
+	content := c.normalizeText(message.Chat())
+	ctx := &Context{
+		Message: message,
+		text:    content,
+	}
+	r := &chatRoute{
+		handler:     c.defHandler,
+		middlewares: []HandlerFunc{},
 	}
+	c.route(ctx, content, r)
+	ctx.middlewares = r.middlewares
+	ctx.handler = r.handler
+	ctx.command = r.command
+	ctx.args = splitArgs(r.rawArgs)
+	ctx.rawArgs = r.rawArgs
+	ctx.params = r.params
+	ctx.timeout = r.timeout
+	return ctx
 }
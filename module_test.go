@@ -0,0 +1,134 @@
+package dingtalkbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+// tracer records the middlewares and handlers which run
+type tracer []string
+
+func (tr *tracer) middleware(name string) HandlerFunc {
+	return func(ctx *Context) {
+		*tr = append(*tr, name)
+		ctx.Next()
+	}
+}
+
+func (tr *tracer) handler(name string) HandlerFunc {
+	return func(ctx *Context) {
+		*tr = append(*tr, name)
+	}
+}
+
+type routeCase struct {
+	text    string
+	trace   []string
+	command string
+	args    []string
+}
+
+// testRoutes routes each text through the chain built by build, and checks
+// what ran and how the command was parsed
+func testRoutes(t *testing.T, build func(tr *tracer) *ChatChain, tests []routeCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			tr := &tracer{}
+			ctx := build(tr).parseContext(toMessage(ChatMessage(groupChat(tt.text))))
+			if ctx.handler != nil {
+				if err := ctx.run(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !reflect.DeepEqual([]string(*tr), tt.trace) {
+				t.Errorf("trace = %q, want %q", *tr, tt.trace)
+			}
+			if ctx.Command() != tt.command {
+				t.Errorf("command = %q, want %q", ctx.Command(), tt.command)
+			}
+			if tt.args != nil && !reflect.DeepEqual(ctx.Args(), tt.args) {
+				t.Errorf("args = %q, want %q", ctx.Args(), tt.args)
+			}
+		})
+	}
+}
+
+func TestChatChainGroups(t *testing.T) {
+	build := func(tr *tracer) *ChatChain {
+		chain := ModuleChatChain().Use(tr.middleware("top")).Default(tr.handler("default"))
+		chain.Handle("deploy", tr.handler("deploy"))
+		k8s := chain.Group("k8s").Use(tr.middleware("k8s"))
+		k8s.Handle("pods", tr.handler("pods"))
+		logs := k8s.Group("logs").Use(tr.middleware("logs")).Default(tr.handler("logs default"))
+		logs.Handle("tail", tr.handler("tail"))
+		return chain
+	}
+	testRoutes(t, build, []routeCase{
+		{text: "/deploy api", trace: []string{"top", "deploy"}, command: "/deploy", args: []string{"api"}},
+		{text: "/k8s pods -n prod", trace: []string{"top", "k8s", "pods"}, command: "/k8s pods", args: []string{"-n", "prod"}},
+		{text: "/k8s logs tail api", trace: []string{"top", "k8s", "logs", "tail"}, command: "/k8s logs tail", args: []string{"api"}},
+		{text: "/k8s logs api", trace: []string{"top", "k8s", "logs", "logs default"}, command: "/k8s logs", args: []string{"api"}},
+		{text: "/k8s logs", trace: []string{"top", "k8s", "logs", "logs default"}, command: "/k8s logs", args: []string{}},
+		// the group has no default, so it's as if it didn't match
+		{text: "/k8s unknown", trace: []string{"top", "default"}, command: "", args: []string{}},
+		{text: "/k8s", trace: []string{"top", "default"}, command: "", args: []string{}},
+		{text: "hello", trace: []string{"top", "default"}, command: ""},
+	})
+}
+
+func TestChatChainGroupAdoptsHandler(t *testing.T) {
+	build := func(tr *tracer) *ChatChain {
+		chain := ModuleChatChain()
+		chain.Handle("k8s", tr.handler("k8s"))
+		chain.Group("k8s").Handle("pods", tr.handler("pods"))
+		return chain
+	}
+	testRoutes(t, build, []routeCase{
+		{text: "/k8s pods", trace: []string{"pods"}, command: "/k8s pods"},
+		{text: "/k8s nodes", trace: []string{"k8s"}, command: "/k8s", args: []string{"nodes"}},
+	})
+}
+
+func TestChatChainPrefixesAndAliases(t *testing.T) {
+	build := func(tr *tracer) *ChatChain {
+		return ModuleChatChain().Prefix("/", "!", "").
+			Handle("deploy", tr.handler("deploy"), WithAliases("部署", "ship")).
+			Default(tr.handler("default"))
+	}
+	testRoutes(t, build, []routeCase{
+		{text: "/deploy api", trace: []string{"deploy"}, command: "/deploy", args: []string{"api"}},
+		{text: "!deploy api", trace: []string{"deploy"}, command: "/deploy", args: []string{"api"}},
+		{text: "deploy api", trace: []string{"deploy"}, command: "/deploy", args: []string{"api"}},
+		{text: "/部署 api", trace: []string{"deploy"}, command: "/deploy", args: []string{"api"}},
+		{text: "ship", trace: []string{"deploy"}, command: "/deploy", args: []string{}},
+		{text: "/DEPLOY api", trace: []string{"default"}, command: ""},
+		{text: "#deploy", trace: []string{"default"}, command: ""},
+		{text: "deployment", trace: []string{"default"}, command: ""},
+	})
+}
+
+func TestChatChainCaseInsensitive(t *testing.T) {
+	register := func(tr *tracer, chain *ChatChain) *ChatChain {
+		chain.Handle("deploy", tr.handler("deploy"), WithAliases("Ship"))
+		chain.Group("k8s").Handle("pods", tr.handler("pods"))
+		chain.HandlePattern("rollback {service}", tr.handler("rollback"))
+		return chain
+	}
+	tests := []routeCase{
+		{text: "/DEPLOY api", trace: []string{"deploy"}, command: "/deploy", args: []string{"api"}},
+		{text: "/ship", trace: []string{"deploy"}, command: "/deploy"},
+		{text: "/K8S PODS", trace: []string{"pods"}, command: "/k8s pods"},
+		{text: "Rollback API", trace: []string{"rollback"}, command: "rollback {service}"},
+	}
+	t.Run("before registering", func(t *testing.T) {
+		testRoutes(t, func(tr *tracer) *ChatChain {
+			return register(tr, ModuleChatChain().CaseInsensitive())
+		}, tests)
+	})
+	t.Run("after registering", func(t *testing.T) {
+		testRoutes(t, func(tr *tracer) *ChatChain {
+			return register(tr, ModuleChatChain()).CaseInsensitive()
+		}, tests)
+	})
+}
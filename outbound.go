@@ -0,0 +1,32 @@
+package dingtalkbot
+
+import (
+	"errors"
+)
+
+var ErrMessageRejected = errors.New("message was rejected by outbound middleware")
+
+// OutboundFunc intercepts a Sendable before it is queued, it can return a
+// modified (or entirely different) message, or an error to reject it.
+// Returning a nil message without error rejects it with ErrMessageRejected.
+// Messages of the library are either a *DingTalkMessage or, for replies
+// through session webhooks, a *WebhookMessage.
+type OutboundFunc func(msg Sendable) (Sendable, error)
+
+func (m *Messenger) useOutbound(outbound OutboundFunc) {
+	m.outbound = append(m.outbound, outbound)
+}
+
+func (m *Messenger) applyOutbound(msg Sendable) (Sendable, error) {
+	for _, outbound := range m.outbound {
+		next, err := outbound(msg)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil {
+			return nil, ErrMessageRejected
+		}
+		msg = next
+	}
+	return msg, nil
+}
@@ -0,0 +1,94 @@
+package dingtalkbot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var patternParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// patternSpace matches any whitespace, \s alone misses full-width spaces
+const patternSpace = `[\s\p{Zs}]+`
+
+// compilePattern turns a pattern like "rollback {service} to {version}" into
+// an anchored regexp, each {name} captures a non-empty text as a named param
+// and each run of spaces matches any whitespace
+func compilePattern(pattern string, caseInsensitive bool) (*regexp.Regexp, error) {
+	sb := &strings.Builder{}
+	sb.WriteString("^")
+	if caseInsensitive {
+		sb.WriteString("(?i)")
+	}
+	last := 0
+	for _, loc := range patternParam.FindAllStringSubmatchIndex(pattern, -1) {
+		sb.WriteString(quotePatternText(pattern[last:loc[0]]))
+		sb.WriteString(fmt.Sprintf(`(?P<%s>.+?)`, pattern[loc[2]:loc[3]]))
+		last = loc[1]
+	}
+	sb.WriteString(quotePatternText(pattern[last:]))
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+func quotePatternText(text string) string {
+	parts := strings.FieldsFunc(text, unicode.IsSpace)
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	quoted := strings.Join(parts, patternSpace)
+	if text != "" && unicode.IsSpace([]rune(text)[0]) {
+		quoted = patternSpace + quoted
+	}
+	if text != "" && strings.TrimRightFunc(text, unicode.IsSpace) != text && quoted != patternSpace {
+		quoted += patternSpace
+	}
+	return quoted
+}
+
+// HandleRegexp routes chat messages which match re to handler when no command
+// matched, the named groups of re are available by Context.Param. Routes are
+// tried in the order they are registered.
+func (c *ChatChain) HandleRegexp(re *regexp.Regexp, handler HandlerFunc, options ...CommandOption) *ChatChain {
+	cmd := &chatCommand{
+		name:    re.String(),
+		handler: handler,
+		pattern: re,
+	}
+	cmd.apply(options)
+	c.patterns = append(c.patterns, cmd)
+	return c
+}
+
+// HandlePattern is HandleRegexp with a pattern like "rollback {service} to
+// {version}", it panics if the pattern can't be compiled
+func (c *ChatChain) HandlePattern(pattern string, handler HandlerFunc, options ...CommandOption) *ChatChain {
+	re, err := compilePattern(pattern, c.caseInsensitive)
+	if err != nil {
+		panic(fmt.Sprintf("invalid pattern %q: %v", pattern, err))
+	}
+	c.HandleRegexp(re, handler, options...)
+	cmd := c.patterns[len(c.patterns)-1]
+	cmd.name, cmd.source = pattern, pattern
+	return c
+}
+
+// matchPattern returns the first pattern route which matches text
+func (c *ChatChain) matchPattern(text string) (*chatCommand, map[string]string, bool) {
+	text = strings.TrimSpace(text)
+	for _, cmd := range c.patterns {
+		matches := cmd.pattern.FindStringSubmatch(text)
+		if matches == nil {
+			continue
+		}
+		params := make(map[string]string)
+		for i, name := range cmd.pattern.SubexpNames() {
+			if name != "" {
+				params[name] = matches[i]
+			}
+		}
+		return cmd, params, true
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,73 @@
+package dingtalkbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		fold    bool
+		text    string
+		want    map[string]string
+	}{
+		{pattern: "rollback {service} to {version}", text: "rollback api to v1.2", want: map[string]string{"service": "api", "version": "v1.2"}},
+		{pattern: "rollback {service} to {version}", text: "rollback  api\tto　v1.2", want: map[string]string{"service": "api", "version": "v1.2"}},
+		{pattern: "rollback {service} to {version}", text: "rollback my api to v1", want: map[string]string{"service": "my api", "version": "v1"}},
+		{pattern: "rollback {service} to {version}", text: "rollback api to", want: nil},
+		{pattern: "rollback {service} to {version}", text: "please rollback api to v1", want: nil},
+		{pattern: "rollback {service} to {version}", text: "Rollback api to v1", want: nil},
+		{pattern: "rollback {service} to {version}", fold: true, text: "Rollback api TO v1", want: map[string]string{"service": "api", "version": "v1"}},
+		{pattern: "price of {item}?", text: "price of tea?", want: map[string]string{"item": "tea"}},
+		{pattern: "price of {item}?", text: "price of tea", want: nil},
+		{pattern: "(a+) {x}", text: "(a+) b", want: map[string]string{"x": "b"}},
+		{pattern: "(a+) {x}", text: "aa b", want: nil},
+		{pattern: "{a}{b}", text: "xyz", want: map[string]string{"a": "x", "b": "yz"}},
+		{pattern: "{a}{b}", text: "x", want: nil},
+		{pattern: "{a}-{b}", text: "x-y-z", want: map[string]string{"a": "x", "b": "y-z"}},
+		{pattern: " status ", text: "status", want: nil},
+		{pattern: " status ", text: " status ", want: map[string]string{}},
+		{pattern: "status", text: "status", want: map[string]string{}},
+		{pattern: "{name}", text: "", want: nil},
+		{pattern: "{not a param}", text: "{not a param}", want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.text, func(t *testing.T) {
+			re, err := compilePattern(tt.pattern, tt.fold)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]string
+			if matches := re.FindStringSubmatch(tt.text); matches != nil {
+				got = map[string]string{}
+				for i, name := range re.SubexpNames() {
+					if name != "" {
+						got[name] = matches[i]
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s (%s) on %q = %v, want %v", tt.pattern, re, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotePatternText(t *testing.T) {
+	tests := map[string]string{
+		"":      ``,
+		" ":     patternSpace,
+		"to":    `to`,
+		" to ":  patternSpace + `to` + patternSpace,
+		"a  b":  `a` + patternSpace + `b`,
+		"a.b":   `a\.b`,
+		"\tfoo": patternSpace + `foo`,
+		"foo　":  `foo` + patternSpace,
+	}
+	for text, want := range tests {
+		if got := quotePatternText(text); got != want {
+			t.Errorf("quotePatternText(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
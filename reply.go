@@ -0,0 +1,143 @@
+package dingtalkbot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrCannotReply = errors.New("message can't be replied")
+
+// ActionCard is a card with a markdown text and buttons, a single button is
+// shown if SingleTitle is set, otherwise 2 to 5 Buttons are shown
+type ActionCard struct {
+	Title       string
+	Text        string
+	SingleTitle string
+	SingleURL   string
+	Buttons     []CardButton
+}
+
+type CardButton struct {
+	Title string
+	URL   string
+}
+
+// replyMessage is a message in both the OpenAPI template and the session
+// webhook formats
+type replyMessage struct {
+	msgKey   string
+	msgParam map[string]string
+	webhook  map[string]any
+}
+
+// Reply answers the chat message with text, it's sent into the group of a
+// group chat, to the sender of a 1:1 chat, or through the session webhook if
+// the sender has no staff id (e.g. external users) and the webhook is valid
+func (c *Context) Reply(text string) error {
+	return c.reply(&replyMessage{
+		msgKey:   "sampleText",
+		msgParam: map[string]string{"content": text},
+		webhook: map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text},
+		},
+	})
+}
+
+// ReplyMarkdown answers the chat message with markdown text
+func (c *Context) ReplyMarkdown(title, text string) error {
+	return c.reply(&replyMessage{
+		msgKey:   "sampleMarkdown",
+		msgParam: map[string]string{"title": title, "text": text},
+		webhook: map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": title, "text": text},
+		},
+	})
+}
+
+// ReplyCard answers the chat message with an action card
+func (c *Context) ReplyCard(card *ActionCard) error {
+	if card.SingleTitle != "" {
+		return c.reply(&replyMessage{
+			msgKey: "sampleActionCard",
+			msgParam: map[string]string{
+				"title":       card.Title,
+				"text":        card.Text,
+				"singleTitle": card.SingleTitle,
+				"singleURL":   card.SingleURL,
+			},
+			webhook: map[string]any{
+				"msgtype": "actionCard",
+				"actionCard": map[string]string{
+					"title":       card.Title,
+					"text":        card.Text,
+					"singleTitle": card.SingleTitle,
+					"singleURL":   card.SingleURL,
+				},
+			},
+		})
+	}
+	if len(card.Buttons) < 2 || len(card.Buttons) > 5 {
+		return fmt.Errorf("action card needs a single button or 2 to 5 buttons, got %d", len(card.Buttons))
+	}
+	msgParam := map[string]string{
+		"title": card.Title,
+		"text":  card.Text,
+	}
+	btns := []map[string]string{}
+	for i, button := range card.Buttons {
+		msgParam[fmt.Sprintf("actionTitle%d", i+1)] = button.Title
+		msgParam[fmt.Sprintf("actionURL%d", i+1)] = button.URL
+		btns = append(btns, map[string]string{"title": button.Title, "actionURL": button.URL})
+	}
+	return c.reply(&replyMessage{
+		msgKey:   fmt.Sprintf("sampleActionCard%d", len(card.Buttons)),
+		msgParam: msgParam,
+		webhook: map[string]any{
+			"msgtype": "actionCard",
+			"actionCard": map[string]any{
+				"title": card.Title,
+				"text":  card.Text,
+				"btns":  btns,
+			},
+		},
+	})
+}
+
+// ReplyImage answers the chat message with the image of photoURL, session
+// webhooks show it in a markdown message
+func (c *Context) ReplyImage(photoURL string) error {
+	return c.reply(&replyMessage{
+		msgKey:   "sampleImageMsg",
+		msgParam: map[string]string{"photoURL": photoURL},
+		webhook: map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": "image", "text": fmt.Sprintf("![image](%s)", photoURL)},
+		},
+	})
+}
+
+// reply picks the target of r by the chat message, see Reply
+func (c *Context) reply(r *replyMessage) error {
+	if c.Message.Type != TypeChat {
+		return ErrCannotReply
+	}
+	chat := c.Chat()
+	switch {
+	case chat.ConversationType == ConversationGroup:
+		return c.Client.Send(c.Client.Message(chat.ConversationId, r.msgKey, r.msgParam))
+	case chat.SenderStaffId != "":
+		msg := c.Client.Message(chat.ConversationId, r.msgKey, r.msgParam)
+		msg.UserIds = []string{chat.SenderStaffId}
+		return c.Client.Send(msg)
+	case chat.SessionWebhook != "" && time.Now().Before(time.UnixMilli(chat.SessionWebhookExpiredTime)):
+		return c.Client.Send(&WebhookMessage{
+			ConversationId: chat.ConversationId,
+			Body:           r.webhook,
+			webhook:        chat.SessionWebhook,
+		})
+	}
+	return ErrCannotReply
+}
@@ -0,0 +1,126 @@
+package dingtalkbot
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/open-dingtalk/dingtalk-stream-sdk-go/chatbot"
+)
+
+func TestReplyTarget(t *testing.T) {
+	valid := time.Now().Add(time.Hour).UnixMilli()
+	expired := time.Now().Add(-time.Minute).UnixMilli()
+	tests := []struct {
+		name     string
+		chat     func(chat *chatbot.BotCallbackDataModel)
+		wantErr  error
+		wantPath string
+		wantBody map[string]any
+	}{
+		{
+			name:     "group",
+			chat:     func(chat *chatbot.BotCallbackDataModel) {},
+			wantPath: openApiSendMessage,
+			wantBody: map[string]any{
+				"msgKey":             "sampleText",
+				"msgParam":           `{"content":"pong"}`,
+				"openConversationId": "cid",
+				"robotCode":          "robot",
+			},
+		},
+		{
+			name: "1:1 with a staff id",
+			chat: func(chat *chatbot.BotCallbackDataModel) {
+				chat.ConversationType = ConversationSingle
+				chat.SessionWebhook, chat.SessionWebhookExpiredTime = "https://oapi.dingtalk.com/robot/sendBySession?session=s", valid
+			},
+			wantPath: openApiSendOtoMessage,
+			wantBody: map[string]any{
+				"msgKey":    "sampleText",
+				"msgParam":  `{"content":"pong"}`,
+				"userIds":   []any{"staff"},
+				"robotCode": "robot",
+			},
+		},
+		{
+			name: "1:1 external user through the session webhook",
+			chat: func(chat *chatbot.BotCallbackDataModel) {
+				chat.ConversationType, chat.SenderStaffId = ConversationSingle, ""
+				chat.SessionWebhook, chat.SessionWebhookExpiredTime = "https://oapi.dingtalk.com/robot/sendBySession?session=s", valid
+			},
+			wantPath: "/robot/sendBySession",
+			wantBody: map[string]any{
+				"msgtype": "text",
+				"text":    map[string]any{"content": "pong"},
+			},
+		},
+		{
+			name: "expired session webhook",
+			chat: func(chat *chatbot.BotCallbackDataModel) {
+				chat.ConversationType, chat.SenderStaffId = ConversationSingle, ""
+				chat.SessionWebhook, chat.SessionWebhookExpiredTime = "https://oapi.dingtalk.com/robot/sendBySession?session=s", expired
+			},
+			wantErr: ErrCannotReply,
+		},
+		{
+			name: "no session webhook",
+			chat: func(chat *chatbot.BotCallbackDataModel) {
+				chat.ConversationType, chat.SenderStaffId = ConversationSingle, ""
+			},
+			wantErr: ErrCannotReply,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t)
+			var gotPath string
+			var gotBody map[string]any
+			client.Messenger.api.tokens = staticTokens("token")
+			client.Transport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				gotPath = req.URL.Path
+				if err := json.NewDecoder(req.Body).Decode(&gotBody); err != nil {
+					return nil, err
+				}
+				return respond(req, "application/json", []byte(`{"errcode":0,"processQueryKey":"key"}`)), nil
+			}))
+
+			chat := groupChat("/ping")
+			tt.chat(chat)
+			ctx := &Context{Message: toMessage(ChatMessage(chat)), Client: client}
+			err := ctx.Reply("pong")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reply = %v, want %v", err, tt.wantErr)
+			}
+			msgs := queued(client, "cid")
+			if tt.wantErr != nil {
+				if len(msgs) != 0 {
+					t.Errorf("queued %d messages, want none", len(msgs))
+				}
+				return
+			}
+			if len(msgs) != 1 {
+				t.Fatalf("queued %d messages, want 1", len(msgs))
+			}
+			if _, err = client.Messenger.api.sendMessage(msgs[0]); err != nil {
+				t.Fatal(err)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if !reflect.DeepEqual(gotBody, tt.wantBody) {
+				t.Errorf("body = %v, want %v", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestReplyEventMessage(t *testing.T) {
+	ctx := &Context{Message: &Message{Type: TypeEvent}, Client: newTestClient(t)}
+	if err := ctx.Reply("pong"); !errors.Is(err, ErrCannotReply) {
+		t.Errorf("Reply = %v, want ErrCannotReply", err)
+	}
+}
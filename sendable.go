@@ -2,9 +2,6 @@ package dingtalkbot
 
 import (
 	"encoding/json"
-	"github.com/mitchellh/mapstructure"
-	"golang.org/x/exp/maps"
-	"reflect"
 )
 
 type Sendable interface {
@@ -12,12 +9,23 @@ type Sendable interface {
 	OpenConversationId() string
 }
 
+// Idempotent can be implemented by a Sendable to let the Messenger suppress
+// duplicated messages which carry the same key
+type Idempotent interface {
+	IdempotencyKey() string
+}
+
+// DingTalkMessage is sent into the group of ConversationId, or to the users
+// of UserIds in 1:1 chats if it's not empty, ConversationId then only keys
+// the rate limit of the message
 type DingTalkMessage struct {
-	MsgKey         string            `json:"msgKey" mapstructure:"msgKey"`
-	MsgParam       map[string]string `json:"msgParam" mapstructure:"msgParam"`
-	ConversationId string            `json:"openConversationId" mapstructure:"openConversationId"`
+	MsgKey         string
+	MsgParam       map[string]string
+	ConversationId string
+	UserIds        []string
 
-	extras map[string]string
+	robotCode      string
+	idempotencyKey string
 }
 
 //goland:noinspection GoMixedReceiverTypes
@@ -26,28 +34,66 @@ func (msg *DingTalkMessage) OpenConversationId() string {
 }
 
 //goland:noinspection GoMixedReceiverTypes
-func (msg DingTalkMessage) MarshalJSON() ([]byte, error) {
-	dst := make(map[string]any)
-	err := mapstructure.Decode(msg, &dst)
-	if err != nil {
-		return nil, err
+func (msg *DingTalkMessage) IdempotencyKey() string {
+	return msg.idempotencyKey
+}
+
+// WithIdempotencyKey marks the message, so another message with the same key
+// is thrown away until the Messenger's dedup TTL expires
+//
+//goland:noinspection GoMixedReceiverTypes
+func (msg *DingTalkMessage) WithIdempotencyKey(key string) *DingTalkMessage {
+	msg.idempotencyKey = key
+	return msg
+}
+
+//goland:noinspection GoMixedReceiverTypes
+func (msg *DingTalkMessage) openApiPath() string {
+	if len(msg.UserIds) > 0 {
+		return openApiSendOtoMessage
 	}
-	dstExtras := make(map[string]any)
-	err = mapstructure.Decode(msg.extras, &dstExtras)
+	return openApiSendMessage
+}
+
+// MarshalJSON encodes the message as the request body of group or 1:1
+// messages, the msgParam is a json string inside the body
+//
+//goland:noinspection GoMixedReceiverTypes
+func (msg DingTalkMessage) MarshalJSON() ([]byte, error) {
+	msgParam, err := json.Marshal(msg.MsgParam)
 	if err != nil {
 		return nil, err
 	}
-	for _, key := range maps.Keys(dstExtras) {
-		dst[key] = dstExtras[key]
+	if len(msg.UserIds) > 0 {
+		return json.Marshal(&otoMessageRequest{
+			MsgKey:    msg.MsgKey,
+			MsgParam:  string(msgParam),
+			UserIds:   msg.UserIds,
+			RobotCode: msg.robotCode,
+		})
 	}
+	return json.Marshal(&groupMessageRequest{
+		MsgKey:             msg.MsgKey,
+		MsgParam:           string(msgParam),
+		OpenConversationId: msg.ConversationId,
+		RobotCode:          msg.robotCode,
+	})
+}
 
-	if msgParam, ok := dst["msgParam"]; ok && reflect.TypeOf(msgParam).Kind() != reflect.String {
-		msgParamBytes, err := json.Marshal(msgParam)
-		if err != nil {
-			return nil, err
-		}
-		dst["msgParam"] = string(msgParamBytes)
-	}
+// WebhookMessage replies through the session webhook of a chat message, which
+// needs no access token. Body is sent as is, e.g. {"msgtype": "text", "text":
+// {"content": "..."}}, so outbound middlewares can inspect and modify it
+type WebhookMessage struct {
+	ConversationId string
+	Body           map[string]any
+
+	webhook string
+}
+
+func (msg *WebhookMessage) OpenConversationId() string {
+	return msg.ConversationId
+}
 
-	return json.Marshal(dst)
+func (msg *WebhookMessage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(msg.Body)
 }
@@ -0,0 +1,29 @@
+package dingtalkbot
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func newBenchmarkMessage() *DingTalkMessage {
+	return &DingTalkMessage{
+		MsgKey: "sampleMarkdown",
+		MsgParam: map[string]string{
+			"title": "deploy finished",
+			"text":  "### deploy finished\n- service: gateway\n- version: v1.2.3",
+		},
+		ConversationId: "cidXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXXX",
+		robotCode:      "dingXXXXXXXXXXXXXXXX",
+	}
+}
+
+func BenchmarkDingTalkMessageMarshal(b *testing.B) {
+	msg := newBenchmarkMessage()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, err := json.Marshal(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package dingtalkbot
+
+import (
+	"time"
+)
+
+// handlerTimeout bounds how long a handler may run, the reply is sent to the
+// chat if it's not empty and the handler timed out
+type handlerTimeout struct {
+	timeout time.Duration
+	reply   string
+}
+
+// override returns t if it's set, otherwise the outer timeout
+func (t handlerTimeout) override(outer handlerTimeout) handlerTimeout {
+	if t.timeout > 0 {
+		return t
+	}
+	return outer
+}
+
+// WithTimeout cancels the Context of the command's handler after timeout, and
+// replies text if it's not empty
+func WithTimeout(timeout time.Duration, text string) CommandOption {
+	return func(cmd *chatCommand) {
+		cmd.timeout = handlerTimeout{timeout: timeout, reply: text}
+	}
+}
+
+// Timeout cancels the Context of the handler after timeout, and replies text
+// if it's not empty
+func (s *Simple) Timeout(timeout time.Duration, text string) *Simple {
+	s.timeout = handlerTimeout{timeout: timeout, reply: text}
+	return s
+}
+
+// Timeout cancels the Context of the handlers after timeout, and replies text
+// if it's not empty
+func (c *Chain) Timeout(timeout time.Duration, text string) *Chain {
+	c.timeout = handlerTimeout{timeout: timeout, reply: text}
+	return c
+}
+
+// Timeout cancels the Context of the commands after timeout, and replies text
+// if it's not empty, commands and groups with their own timeout override it
+func (c *ChatChain) Timeout(timeout time.Duration, text string) *ChatChain {
+	c.timeout = handlerTimeout{timeout: timeout, reply: text}
+	return c
+}
@@ -0,0 +1,178 @@
+package dingtalkbot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	iTokenRefreshAhead = 5 * time.Minute
+	iTokenMinBackoff   = time.Second
+	iTokenMaxBackoff   = time.Minute
+)
+
+// TokenSource provides the access token for OpenAPI requests, it must be safe
+// for concurrent use
+type TokenSource interface {
+	// Token returns a valid access token, refreshing it if needed
+	Token() (string, error)
+}
+
+// TokenInvalidator can be implemented by a TokenSource to drop a token which
+// DingTalk rejected before its expiry, so the next Token call refreshes it
+type TokenInvalidator interface {
+	Invalidate(token string)
+}
+
+// TokenStore persists an access token, so it can be shared by several
+// processes which use the same app credential
+type TokenStore interface {
+	Load() (token string, expiry time.Time, err error)
+	Save(token string, expiry time.Time) error
+}
+
+type appTokenSource struct {
+	mutex *sync.Mutex
+
+	http         *httpClient
+	clientId     string
+	clientSecret string
+	store        TokenStore
+
+	token   string
+	expiry  time.Time
+	invalid string
+
+	backoff   time.Duration
+	retryAt   time.Time
+	lastError error
+}
+
+// newTokenSource returns the default TokenSource which fetches the access token
+// of an app credential and refreshes it before expiry, see setStore to share
+// the token
+func newTokenSource(http *httpClient, clientId, clientSecret string) *appTokenSource {
+	return &appTokenSource{
+		mutex:        &sync.Mutex{},
+		http:         http,
+		clientId:     clientId,
+		clientSecret: clientSecret,
+	}
+}
+
+func (s *appTokenSource) setStore(store TokenStore) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.store = store
+}
+
+func (s *appTokenSource) valid(expiry time.Time) bool {
+	return time.Now().Add(iTokenRefreshAhead).Before(expiry)
+}
+
+func (s *appTokenSource) Token() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.token != "" && s.valid(s.expiry) {
+		return s.token, nil
+	}
+	if s.store != nil {
+		token, expiry, err := s.store.Load()
+		if err != nil {
+			logger.Warn("failed to load access token from store", "err", err)
+		} else if token != "" && token != s.invalid && s.valid(expiry) {
+			s.token, s.expiry = token, expiry
+			return s.token, nil
+		}
+	}
+	// still backing off from the last failure
+	if time.Now().Before(s.retryAt) {
+		return "", s.lastError
+	}
+
+	token, expireSec, err := s.http.getAccessToken(s.clientId, s.clientSecret)
+	if err != nil {
+		s.backoff = min(max(s.backoff*2, iTokenMinBackoff), iTokenMaxBackoff)
+		s.retryAt = time.Now().Add(s.backoff)
+		s.lastError = err
+		return "", err
+	}
+	s.backoff, s.retryAt, s.lastError = 0, time.Time{}, nil
+	s.token = token
+	s.expiry = time.Now().Add(time.Duration(expireSec) * time.Second)
+
+	if s.store != nil {
+		err = s.store.Save(s.token, s.expiry)
+		if err != nil {
+			logger.Warn("failed to save access token to store", "err", err)
+		}
+	}
+	return s.token, nil
+}
+
+func (s *appTokenSource) Invalidate(token string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.invalid = token
+	if s.token == token {
+		s.token, s.expiry = "", time.Time{}
+		s.backoff, s.retryAt, s.lastError = 0, time.Time{}, nil
+	}
+}
+
+type fileTokenStore struct {
+	path string
+}
+
+type fileToken struct {
+	AccessToken string    `json:"accessToken"`
+	Expiry      time.Time `json:"expiry"`
+}
+
+// NewFileTokenStore returns a TokenStore which keeps the access token in a
+// json file, the file is replaced atomically on every save
+func NewFileTokenStore(path string) TokenStore {
+	return &fileTokenStore{path: path}
+}
+
+func (s *fileTokenStore) Load() (token string, expiry time.Time, err error) {
+	bytes, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return
+	}
+	ft := fileToken{}
+	err = json.Unmarshal(bytes, &ft)
+	if err != nil {
+		return
+	}
+	return ft.AccessToken, ft.Expiry, nil
+}
+
+func (s *fileTokenStore) Save(token string, expiry time.Time) error {
+	bytes, err := json.Marshal(fileToken{AccessToken: token, Expiry: expiry})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(bytes)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
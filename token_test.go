@@ -0,0 +1,151 @@
+package dingtalkbot
+
+import (
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenStub returns a token source whose access token endpoint is answered
+// by respond, fetches counts the calls
+func newTokenStub(fetches *atomic.Int32, respond func(req *http.Request) (*http.Response, error)) *appTokenSource {
+	hc := newHttpClient()
+	hc.transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		fetches.Add(1)
+		return respond(req)
+	})
+	hc.apply()
+	return newTokenSource(hc, "app-key", "app-secret")
+}
+
+func respondToken(token string) func(req *http.Request) (*http.Response, error) {
+	return func(req *http.Request) (*http.Response, error) {
+		return respond(req, "application/json", []byte(`{"accessToken":"`+token+`","expireIn":7200}`)), nil
+	}
+}
+
+type memoryTokenStore struct {
+	token  string
+	expiry time.Time
+}
+
+func (s *memoryTokenStore) Load() (string, time.Time, error) {
+	return s.token, s.expiry, nil
+}
+
+func (s *memoryTokenStore) Save(token string, expiry time.Time) error {
+	s.token, s.expiry = token, expiry
+	return nil
+}
+
+func TestTokenConcurrentFetchOnce(t *testing.T) {
+	fetches := &atomic.Int32{}
+	slow := respondToken("token-1")
+	tokens := newTokenStub(fetches, func(req *http.Request) (*http.Response, error) {
+		time.Sleep(20 * time.Millisecond)
+		return slow(req)
+	})
+
+	wg := &sync.WaitGroup{}
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = tokens.Token()
+		}(i)
+	}
+	wg.Wait()
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+	for _, token := range results {
+		if token != "token-1" {
+			t.Fatalf("tokens = %q, want all token-1", results)
+		}
+	}
+}
+
+func TestTokenBackoff(t *testing.T) {
+	fetches := &atomic.Int32{}
+	tokens := newTokenStub(fetches, func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+
+	for i := 0; i < 5; i++ {
+		if _, err := tokens.Token(); err == nil {
+			t.Fatal("Token succeeded, want error")
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times while backing off, want 1", n)
+	}
+	if tokens.backoff != iTokenMinBackoff {
+		t.Errorf("backoff = %v, want %v", tokens.backoff, iTokenMinBackoff)
+	}
+
+	// the backoff doubles on every failure up to the max
+	for _, want := range []time.Duration{2 * time.Second, 4 * time.Second} {
+		tokens.retryAt = time.Time{}
+		_, _ = tokens.Token()
+		if tokens.backoff != want {
+			t.Errorf("backoff = %v, want %v", tokens.backoff, want)
+		}
+	}
+	tokens.backoff = iTokenMaxBackoff
+	tokens.retryAt = time.Time{}
+	_, _ = tokens.Token()
+	if tokens.backoff != iTokenMaxBackoff {
+		t.Errorf("backoff = %v, want the max %v", tokens.backoff, iTokenMaxBackoff)
+	}
+}
+
+func TestTokenInvalidateSkipsStoredToken(t *testing.T) {
+	fetches := &atomic.Int32{}
+	tokens := newTokenStub(fetches, respondToken("fresh"))
+	store := &memoryTokenStore{token: "stale", expiry: time.Now().Add(time.Hour)}
+	tokens.setStore(store)
+
+	if token, _ := tokens.Token(); token != "stale" {
+		t.Fatalf("token = %q, want the stored one", token)
+	}
+	if n := fetches.Load(); n != 0 {
+		t.Fatalf("fetched %d times, want the stored token", n)
+	}
+
+	tokens.Invalidate("stale")
+	if token, _ := tokens.Token(); token != "fresh" {
+		t.Errorf("token after Invalidate = %q, want fresh", token)
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+	if store.token != "fresh" {
+		t.Errorf("stored token = %q, want fresh", store.token)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"))
+	token, expiry, err := store.Load()
+	if err != nil || token != "" || !expiry.IsZero() {
+		t.Fatalf("Load of a missing file = %q, %v, %v", token, expiry, err)
+	}
+
+	want := time.Now().Add(time.Hour).Round(time.Second)
+	if err = store.Save("token-1", want); err != nil {
+		t.Fatal(err)
+	}
+	token, expiry, err = store.Load()
+	if err != nil || token != "token-1" || !expiry.Equal(want) {
+		t.Errorf("Load = %q, %v, %v, want token-1, %v", token, expiry, err, want)
+	}
+}
@@ -0,0 +1,335 @@
+package dingtalkbot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var ErrNoRecordedResponse = errors.New("no recorded response for request")
+
+// recordBodyLimit is the max size of a recorded response body, larger bodies
+// are streamed to the caller without being recorded
+const recordBodyLimit = 1 << 20
+
+// downloadPlaceholder replaces the signed download urls in a recording, the
+// file is recorded under the placeholder so it can be replayed
+const downloadPlaceholder = "https://download.redacted.invalid/%s"
+
+// redacted values never land in a recording
+var (
+	redactedHeaders = []string{"x-acs-dingtalk-access-token"}
+	// the session of webhook replies and the signed urls of downloads are
+	// credentials too
+	redactedQueries = []string{"access_token", "session", "signature", "ossaccesskeyid", "security-token",
+		"x-oss-signature", "x-oss-credential", "x-oss-security-token"}
+	redactedBody = regexp.MustCompile(`("(?:appSecret|accessToken|access_token)"\s*:\s*)"[^"]*"`)
+	downloadBody = regexp.MustCompile(`("downloadUrl"\s*:\s*)("(?:[^"\\]|\\.)*")`)
+)
+
+// exchange is one line of a recording
+type exchange struct {
+	Method         string      `json:"method"`
+	URL            string      `json:"url"`
+	RequestHeader  http.Header `json:"requestHeader"`
+	RequestBody    *body       `json:"requestBody"`
+	StatusCode     int         `json:"statusCode"`
+	ResponseHeader http.Header `json:"responseHeader"`
+	ResponseBody   *body       `json:"responseBody"`
+}
+
+// body keeps text as is, and falls back to base64 for binary content
+type body struct {
+	Text   string `json:"text,omitempty"`
+	Base64 string `json:"base64,omitempty"`
+}
+
+func newBody(data []byte) *body {
+	if utf8.Valid(data) {
+		return &body{Text: string(data)}
+	}
+	return &body{Base64: base64.StdEncoding.EncodeToString(data)}
+}
+
+func (b *body) bytes() ([]byte, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if b.Base64 != "" {
+		return base64.StdEncoding.DecodeString(b.Base64)
+	}
+	return []byte(b.Text), nil
+}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for key := range query {
+		if contains(redactedQueries, strings.ToLower(key)) {
+			query.Del(key)
+		}
+	}
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// exchangeKey identifies a request, secrets in the url are ignored
+func exchangeKey(method string, u *url.URL) string {
+	return method + " " + redactURL(u)
+}
+
+func redactBody(data []byte) []byte {
+	return redactedBody.ReplaceAll(data, []byte(`$1"REDACTED"`))
+}
+
+// isJSON reports whether contentType is json, only json responses are recorded
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// peekBody reads rc for recording and returns a body which replays it, data
+// is nil if rc is larger than recordBodyLimit, rc is streamed as is then
+func peekBody(rc io.ReadCloser) (data []byte, body io.ReadCloser, err error) {
+	if rc == nil || rc == http.NoBody {
+		return nil, rc, nil
+	}
+	data, err = io.ReadAll(io.LimitReader(rc, recordBodyLimit+1))
+	if err != nil {
+		_ = rc.Close()
+		return nil, nil, err
+	}
+	if len(data) > recordBodyLimit {
+		return nil, struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), rc), rc}, nil
+	}
+	_ = rc.Close()
+	return data, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func readBody(rc io.ReadCloser) ([]byte, error) {
+	if rc == nil || rc == http.NoBody {
+		return nil, nil
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// RecordTransport appends every request/response pair which passes through it
+// to a jsonl file, access tokens, app secrets and webhook sessions are
+// redacted. Download urls are replaced by placeholders which the downloaded
+// files are recorded under. Response bodies over 1 MiB are streamed without
+// being recorded, their replay has an empty body.
+type RecordTransport struct {
+	mutex *sync.Mutex
+	base  http.RoundTripper
+	file  *os.File
+	// downloads maps the download urls seen in responses to their placeholders
+	downloads map[string]string
+}
+
+// NewRecordTransport records into path, the requests are sent by base or
+// http.DefaultTransport if it's nil
+func NewRecordTransport(path string, base http.RoundTripper) (*RecordTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordTransport{
+		mutex:     &sync.Mutex{},
+		base:      base,
+		file:      file,
+		downloads: make(map[string]string),
+	}, nil
+}
+
+// redactDownloads replaces the download urls in data by placeholders, and
+// remembers them for the following downloads
+func (t *RecordTransport) redactDownloads(data []byte) []byte {
+	return downloadBody.ReplaceAllFunc(data, func(match []byte) []byte {
+		groups := downloadBody.FindSubmatch(match)
+		var raw string
+		if json.Unmarshal(groups[2], &raw) != nil {
+			return match
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			return match
+		}
+		sum := sha256.Sum256([]byte(raw))
+		placeholder := fmt.Sprintf(downloadPlaceholder, hex.EncodeToString(sum[:8]))
+		t.mutex.Lock()
+		t.downloads[u.String()] = placeholder
+		t.mutex.Unlock()
+		return append(groups[1], fmt.Sprintf("%q", placeholder)...)
+	})
+}
+
+// recordedURL is the url of req in the recording
+func (t *RecordTransport) recordedURL(req *http.Request) string {
+	t.mutex.Lock()
+	placeholder, ok := t.downloads[req.URL.String()]
+	t.mutex.Unlock()
+	if ok {
+		return placeholder
+	}
+	return redactURL(req.URL)
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	var respBody *body
+	data, peeked, err := peekBody(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = peeked
+	if data != nil {
+		if isJSON(resp.Header.Get("Content-Type")) {
+			data = t.redactDownloads(redactBody(data))
+		}
+		respBody = newBody(data)
+	}
+
+	reqHeader := req.Header.Clone()
+	for _, key := range redactedHeaders {
+		if reqHeader.Get(key) != "" {
+			reqHeader.Set(key, "REDACTED")
+		}
+	}
+	err = t.write(&exchange{
+		Method:         req.Method,
+		URL:            t.recordedURL(req),
+		RequestHeader:  reqHeader,
+		RequestBody:    newBody(redactBody(reqBody)),
+		StatusCode:     resp.StatusCode,
+		ResponseHeader: resp.Header,
+		ResponseBody:   respBody,
+	})
+	if err != nil {
+		logger.Warn("failed to record http exchange", "err", err)
+	}
+	return resp, nil
+}
+
+func (t *RecordTransport) write(ex *exchange) error {
+	line, err := json.Marshal(ex)
+	if err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, err = t.file.Write(append(line, '\n'))
+	return err
+}
+
+func (t *RecordTransport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.file.Close()
+}
+
+// ReplayTransport answers requests from a recording of RecordTransport without
+// any network access, requests to the same method and url are answered in
+// the recorded order
+type ReplayTransport struct {
+	mutex     *sync.Mutex
+	exchanges map[string][]*exchange
+}
+
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	t := &ReplayTransport{
+		mutex:     &sync.Mutex{},
+		exchanges: make(map[string][]*exchange),
+	}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		ex := &exchange{}
+		err = json.Unmarshal(scanner.Bytes(), ex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording at line %d: %w", line, err)
+		}
+		u, err := url.Parse(ex.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recording at line %d: %w", line, err)
+		}
+		key := exchangeKey(ex.Method, u)
+		t.exchanges[key] = append(t.exchanges[key], ex)
+	}
+	return t, scanner.Err()
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, err := readBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	key := exchangeKey(req.Method, req.URL)
+
+	t.mutex.Lock()
+	recorded := t.exchanges[key]
+	if len(recorded) == 0 {
+		t.mutex.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrNoRecordedResponse, key)
+	}
+	ex := recorded[0]
+	t.exchanges[key] = recorded[1:]
+	t.mutex.Unlock()
+
+	respBody, err := ex.ResponseBody.bytes()
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", ex.StatusCode, http.StatusText(ex.StatusCode)),
+		StatusCode:    ex.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        ex.ResponseHeader.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package dingtalkbot
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func respond(req *http.Request, contentType string, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {contentType}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+var smallFile = []byte{0x89, 'P', 'N', 'G', 0xff, 0x00}
+
+// dingtalkStub answers the endpoints which the recording test calls
+var dingtalkStub = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+	switch req.URL.Path {
+	case openApiGetAccessToken:
+		return respond(req, "application/json", []byte(`{"accessToken":"real-token","expireIn":7200}`)), nil
+	case openApiSendMessage:
+		return respond(req, "application/json; charset=utf-8", []byte(`{"processQueryKey":"query-key"}`)), nil
+	case openApiDownloadMessageFile:
+		body, _ := io.ReadAll(req.Body)
+		file := "small"
+		if strings.Contains(string(body), "large") {
+			file = "large"
+		}
+		return respond(req, "application/json", []byte(`{"downloadUrl":"https://files.example.com/`+file+
+			`?OSSAccessKeyId=real-key-id\u0026Expires=1700000000\u0026Signature=real-signature"}`)), nil
+	case "/small":
+		return respond(req, "image/png", smallFile), nil
+	case "/large":
+		return respond(req, "application/octet-stream", bytes.Repeat([]byte{0xff}, recordBodyLimit+10)), nil
+	case "/v1.0/robot/sendBySession":
+		return respond(req, "application/json", []byte(`{"errcode":0}`)), nil
+	}
+	return nil, errors.New("unexpected request " + req.URL.String())
+})
+
+func newTransportApi(transport http.RoundTripper) *openApi {
+	hc := newHttpClient()
+	hc.transport = transport
+	hc.apply()
+	return &openApi{
+		http:   hc,
+		tokens: newTokenSource(hc, "app-key", "real-secret"),
+	}
+}
+
+func download(t *testing.T, api *openApi, downloadCode string) []byte {
+	t.Helper()
+	download, err := api.downloadMessageFile(downloadCode, "robot", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer download.Body.Close()
+	data, err := io.ReadAll(download.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	recorder, err := NewRecordTransport(path, dingtalkStub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := newTransportApi(recorder)
+	msg := newBenchmarkMessage()
+	key, err := api.sendMessage(msg)
+	if err != nil || key != "query-key" {
+		t.Fatalf("recorded sendMessage = %q, %v", key, err)
+	}
+	webhook := &WebhookMessage{
+		ConversationId: "cid",
+		Body:           map[string]any{"msgtype": "text"},
+		webhook:        "https://oapi.dingtalk.com/v1.0/robot/sendBySession?session=real-session",
+	}
+	if _, err = api.sendMessage(webhook); err != nil {
+		t.Fatal(err)
+	}
+	if data := download(t, api, "small"); !bytes.Equal(data, smallFile) {
+		t.Fatalf("downloaded %v, want %v", data, smallFile)
+	}
+	if data := download(t, api, "large"); len(data) != recordBodyLimit+10 {
+		t.Fatalf("downloaded %d bytes, want %d", len(data), recordBodyLimit+10)
+	}
+	if err = recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recording, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"real-secret", "real-token", "real-session", "real-key-id", "real-signature"} {
+		if strings.Contains(string(recording), secret) {
+			t.Errorf("recording leaks %q:\n%s", secret, recording)
+		}
+	}
+	if len(recording) > recordBodyLimit {
+		t.Errorf("recording has %d bytes, the large download shouldn't be recorded", len(recording))
+	}
+
+	replayer, err := NewReplayTransport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	api = newTransportApi(replayer)
+	key, err = api.sendMessage(msg)
+	if err != nil || key != "query-key" {
+		t.Fatalf("replayed sendMessage = %q, %v", key, err)
+	}
+	if _, err = api.sendMessage(webhook); err != nil {
+		t.Fatalf("replayed webhook: %v", err)
+	}
+	if data := download(t, api, "small"); !bytes.Equal(data, smallFile) {
+		t.Errorf("replayed download = %v, want %v", data, smallFile)
+	}
+	if data := download(t, api, "large"); len(data) != 0 {
+		t.Errorf("replayed large download has %d bytes, want none", len(data))
+	}
+	if token, _ := api.tokens.Token(); token != "REDACTED" {
+		t.Errorf("replayed token = %q, want the placeholder", token)
+	}
+	_, err = api.sendMessage(msg)
+	if !errors.Is(err, ErrNoRecordedResponse) {
+		t.Errorf("sendMessage beyond the recording = %v, want ErrNoRecordedResponse", err)
+	}
+}
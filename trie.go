@@ -0,0 +1,116 @@
+package dingtalkbot
+
+import (
+	"slices"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// commandTrie matches the longest registered command at the start of a text
+// in time proportional to the text, not to the number of commands
+type commandTrie[T any] struct {
+	mutex *sync.RWMutex
+	root  *trieNode[T]
+	// fold makes the commands case-insensitive
+	fold bool
+}
+
+type trieNode[T any] struct {
+	children map[rune]*trieNode[T]
+	value    T
+	ok       bool
+}
+
+func newCommandTrie[T any](fold bool) *commandTrie[T] {
+	return &commandTrie[T]{
+		mutex: &sync.RWMutex{},
+		root:  &trieNode[T]{},
+		fold:  fold,
+	}
+}
+
+func (t *commandTrie[T]) key(r rune) rune {
+	if t.fold {
+		return unicode.ToLower(r)
+	}
+	return r
+}
+
+func (t *commandTrie[T]) Put(command string, value T) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	node := t.root
+	for _, r := range command {
+		r = t.key(r)
+		if node.children == nil {
+			node.children = make(map[rune]*trieNode[T])
+		}
+		child, ok := node.children[r]
+		if !ok {
+			child = &trieNode[T]{}
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.value, node.ok = value, true
+}
+
+func (t *commandTrie[T]) Get(command string) (value T, ok bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	node := t.root
+	for _, r := range command {
+		node = node.children[t.key(r)]
+		if node == nil {
+			return
+		}
+	}
+	return node.value, node.ok
+}
+
+// Match returns the value of the longest command which text starts with and
+// which is followed by a space or the end of text, rest is the text after the
+// command and its following space
+func (t *commandTrie[T]) Match(text string) (value T, rest string, ok bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	node := t.root
+	for i, r := range text {
+		if node.ok && unicode.IsSpace(r) {
+			value, rest, ok = node.value, text[i+utf8.RuneLen(r):], true
+		}
+		node = node.children[t.key(r)]
+		if node == nil {
+			return
+		}
+	}
+	if node.ok {
+		value, rest, ok = node.value, "", true
+	}
+	return
+}
+
+// Each calls f for every command in lexical order until f returns false
+func (t *commandTrie[T]) Each(f func(command string, value T) bool) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	t.root.each([]rune{}, f)
+}
+
+func (n *trieNode[T]) each(prefix []rune, f func(string, T) bool) bool {
+	if n.ok && !f(string(prefix), n.value) {
+		return false
+	}
+	keys := make([]rune, 0, len(n.children))
+	for r := range n.children {
+		keys = append(keys, r)
+	}
+	slices.Sort(keys)
+	for _, r := range keys {
+		if !n.children[r].each(append(prefix, r), f) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package dingtalkbot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandTrieMatch(t *testing.T) {
+	commands := []string{"/deploy", "/deploy-prod", "/deploy prod", "/状态"}
+	tests := []struct {
+		fold    bool
+		text    string
+		command string
+		rest    string
+		ok      bool
+	}{
+		{text: "/deploy", command: "/deploy", rest: "", ok: true},
+		{text: "/deploy staging now", command: "/deploy", rest: "staging now", ok: true},
+		{text: "/deploy-prod", command: "/deploy-prod", rest: "", ok: true},
+		{text: "/deploy-prod v1", command: "/deploy-prod", rest: "v1", ok: true},
+		{text: "/deploy-prodx", ok: false},
+		{text: "/deployx", ok: false},
+		{text: "/deploy prod", command: "/deploy prod", rest: "", ok: true},
+		{text: "/deploy prod v1", command: "/deploy prod", rest: "v1", ok: true},
+		{text: "/deploy production", command: "/deploy", rest: "production", ok: true},
+		{text: "/deploy\tv1", command: "/deploy", rest: "v1", ok: true},
+		{text: "/deploy　v1", command: "/deploy", rest: "v1", ok: true},
+		{text: "/状态 all", command: "/状态", rest: "all", ok: true},
+		{text: "/dep", ok: false},
+		{text: "", ok: false},
+		{text: "/DEPLOY v1", ok: false},
+		{fold: true, text: "/DEPLOY v1", command: "/deploy", rest: "v1", ok: true},
+		{fold: true, text: "/Deploy-Prod", command: "/deploy-prod", rest: "", ok: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			trie := newCommandTrie[string](tt.fold)
+			for _, command := range commands {
+				trie.Put(command, command)
+			}
+			command, rest, ok := trie.Match(tt.text)
+			if command != tt.command || rest != tt.rest || ok != tt.ok {
+				t.Errorf("Match(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.text, command, rest, ok, tt.command, tt.rest, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCommandTrieEach(t *testing.T) {
+	trie := newCommandTrie[int](false)
+	for i, command := range []string{"/b", "/a", "/ab", "/a b"} {
+		trie.Put(command, i)
+	}
+	got := []string{}
+	trie.Each(func(command string, _ int) bool {
+		got = append(got, command)
+		return true
+	})
+	want := []string{"/a", "/a b", "/ab", "/b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Each = %q, want %q", got, want)
+	}
+}